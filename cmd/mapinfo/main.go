@@ -0,0 +1,180 @@
+// Command mapinfo shows and edits the metadata of maps.
+//
+// Usage:
+//
+//	mapinfo [flags] map.tmap|map.amap|map.json...
+//
+// Without flags the size and metadata of each map is printed. The flags that set metadata change
+// a single map and save it back to the same file, which also updates its modified time.
+// -compress and -checksum choose how a .tmap file is packed and checked when it is saved back.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunkink29/3dpacman/maps"
+)
+
+func main() {
+	title := flag.String("title", "", "set the title")
+	author := flag.String("author", "", "set the author")
+	description := flag.String("description", "", "set the description")
+	par := flag.Int("par", 0, "set the par score")
+	levels := flag.String("levels", "", "set the recommended levels as a comma separated list")
+	thumbnail := flag.String("thumbnail", "", "set the thumbnail from a PNG file")
+	extract := flag.String("extract-thumbnail", "", "write the thumbnail to a PNG file")
+	compress := flag.String("compress", "", "pack the tiles of a .tmap file with none, rle or deflate")
+	checksum := flag.String("checksum", "", "end a .tmap file with a none, crc32 or sha256 checksum")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: mapinfo [flags] map.tmap|map.amap|map.json...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	edit := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "extract-thumbnail" {
+			edit = true
+		}
+	})
+	if (edit || *extract != "") && flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Only one map can be changed at a time")
+		os.Exit(2)
+	}
+
+	for _, filename := range flag.Args() {
+		curMap, err := maps.Load(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if edit {
+			meta := curMap.GetMetadata()
+			flag.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "title":
+					meta.Title = *title
+				case "author":
+					meta.Author = *author
+				case "description":
+					meta.Description = *description
+				case "par":
+					meta.ParScore = *par
+				case "levels":
+					meta.Levels, err = parseLevels(*levels)
+				}
+			})
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			if *thumbnail != "" {
+				if meta.Thumbnail, err = ioutil.ReadFile(*thumbnail); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+			}
+			if err := curMap.SetMetadata(meta); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			options := curMap.GetFileOptions()
+			if *compress != "" {
+				var ok bool
+				if options.Compression, ok = maps.ParseCompression(*compress); !ok {
+					fmt.Fprintln(os.Stderr, "unknown compression", *compress)
+					os.Exit(2)
+				}
+			}
+			if *checksum != "" {
+				var ok bool
+				if options.Checksum, ok = maps.ParseChecksum(*checksum); !ok {
+					fmt.Fprintln(os.Stderr, "unknown checksum", *checksum)
+					os.Exit(2)
+				}
+			}
+			curMap.SetFileOptions(options)
+			if err := curMap.Save(filename); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		if *extract != "" {
+			meta := curMap.GetMetadata()
+			if len(meta.Thumbnail) == 0 {
+				fmt.Fprintln(os.Stderr, filename, "has no thumbnail")
+				os.Exit(1)
+			}
+			if err := ioutil.WriteFile(*extract, meta.Thumbnail, 0644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		printInfo(filename, curMap)
+	}
+}
+
+func printInfo(filename string, curMap *maps.Map) {
+	size := curMap.GetSize()
+	meta := curMap.GetMetadata()
+	fmt.Println(filename)
+	fmt.Println("  size:", size[0], "x", size[1])
+	if strings.HasSuffix(filename, ".tmap") {
+		options := curMap.GetFileOptions()
+		fmt.Println("  compression:", options.Compression)
+		fmt.Println("  checksum:", options.Checksum)
+	}
+	printField := func(name string, value string) {
+		if value != "" {
+			fmt.Printf("  %s: %s\n", name, value)
+		}
+	}
+	printTime := func(name string, t time.Time) {
+		if !t.IsZero() {
+			printField(name, t.Local().Format(time.RFC1123))
+		}
+	}
+	printField("title", meta.Title)
+	printField("author", meta.Author)
+	printField("description", strings.Replace(meta.Description, "\n", "\n    ", -1))
+	printTime("created", meta.Created)
+	printTime("modified", meta.Modified)
+	if len(meta.Levels) > 0 {
+		printField("levels", strings.Trim(fmt.Sprint(meta.Levels), "[]"))
+	}
+	if meta.ParScore != 0 {
+		printField("par", strconv.Itoa(meta.ParScore))
+	}
+	if len(meta.Thumbnail) > 0 {
+		img, err := curMap.GetThumbnail()
+		if err != nil {
+			printField("thumbnail", err.Error())
+		} else {
+			bounds := img.Bounds()
+			printField("thumbnail", fmt.Sprint(bounds.Dx(), " x ", bounds.Dy(), " PNG"))
+		}
+	}
+}
+
+func parseLevels(list string) ([]int, error) {
+	var levels []int
+	for _, field := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' }) {
+		level, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("bad level %q", field))
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
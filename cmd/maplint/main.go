@@ -0,0 +1,59 @@
+// Command maplint checks maps for mistakes with maps.Validate.
+//
+// Usage:
+//
+//	maplint [-q] map.tmap|map.amap|map.json...
+//
+// Each issue is printed as file:x,y: kind: message. The exit status is 1 if any map has an issue
+// or can not be loaded, so it can be used to check maps before they are merged.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sunkink29/3dpacman/maps"
+)
+
+func main() {
+	quiet := flag.Bool("q", false, "only print the names of maps with issues")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: maplint [-q] map.tmap|map.amap|map.json...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, filename := range flag.Args() {
+		curMap, err := maps.Load(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		issues := maps.Validate(curMap)
+		if len(issues) == 0 {
+			continue
+		}
+		failed = true
+		if *quiet {
+			fmt.Println(filename)
+			continue
+		}
+		for _, issue := range issues {
+			if issue.Pos[0] < 0 {
+				fmt.Printf("%s: %s: %s\n", filename, issue.Kind, issue.Message)
+			} else {
+				fmt.Printf("%s:%d,%d: %s: %s\n", filename, issue.Pos[0], issue.Pos[1], issue.Kind, issue.Message)
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,56 @@
+// Command tiled2tmap converts a map made with the Tiled map editor into a .tmap file.
+//
+// Usage:
+//
+//	tiled2tmap [-o output.tmap] map.tmx|map.tmj
+//
+// Without -o the map is saved next to the input with the .tmap extension.
+// See the tiled package for how Tiled tiles and objects are turned into map tiles and spawns and
+// which map properties become its metadata, use mapinfo to see all of it.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sunkink29/3dpacman/maps/tiled"
+)
+
+func main() {
+	output := flag.String("o", "", "the .tmap file to write")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: tiled2tmap [-o output.tmap] map.tmx|map.tmj")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	input := flag.Arg(0)
+	newMap, err := tiled.Import(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + ".tmap"
+	}
+	if err := newMap.SaveToFile(*output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	size := newMap.GetSize()
+	fmt.Println("Saved", size[0], "x", size[1], "map to", *output)
+	meta := newMap.GetMetadata()
+	if meta.Title != "" {
+		fmt.Println("  title:", meta.Title)
+	}
+	if meta.Author != "" {
+		fmt.Println("  author:", meta.Author)
+	}
+}
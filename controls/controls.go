@@ -0,0 +1,55 @@
+package controls
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+
+	"github.com/sunkink29/3dpacman/input"
+)
+
+// GetMovement returns the direction the player is holding with the arrow keys
+func GetMovement() [2]int {
+	return heldMovement
+}
+
+var heldMovement [2]int
+var lastPress int
+
+// RegisterPlayerBindings makes the arrow keys set the direction returned by GetMovement
+func RegisterPlayerBindings() {
+	input.RegisterKeyBinding(glfw.KeyUp, "Move Player Up", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Press {
+			heldMovement[0] = 0
+			heldMovement[1] = -1
+			lastPress = 1
+		} else if action == glfw.Release && lastPress == 1 {
+			heldMovement[1] = 0
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyDown, "Move Player Down", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Press {
+			heldMovement[0] = 0
+			heldMovement[1] = 1
+			lastPress = 2
+		} else if action == glfw.Release && lastPress == 2 {
+			heldMovement[1] = 0
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyLeft, "Move Player Left", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Press {
+			heldMovement[0] = -1
+			heldMovement[1] = 0
+			lastPress = 3
+		} else if action == glfw.Release && lastPress == 3 {
+			heldMovement[0] = 0
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyRight, "Move Player Right", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Press {
+			heldMovement[0] = 1
+			heldMovement[1] = 0
+			lastPress = 4
+		} else if action == glfw.Release && lastPress == 4 {
+			heldMovement[0] = 0
+		}
+	})
+}
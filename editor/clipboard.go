@@ -0,0 +1,92 @@
+package editor
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/rendering/tiles"
+)
+
+// the rectangle picked with the select tool, from the tile the drag started on to the one it ended on
+var selection struct {
+	active     bool
+	start, end [2]int
+}
+
+// the tiles last copied or cut, or the stamp picked from the library, placed with Ctrl+V
+var clipboard *maps.Stamp
+
+// the tile under the cursor, where the clipboard is shown while selecting and pasted
+var (
+	hoverPos   [2]int
+	hoverOnMap bool
+)
+
+var (
+	selectionColor = mgl32.Vec4{0, 1, 1, 1}
+	pasteColor     = mgl32.Vec4{1, 1, 0, 1}
+)
+
+// selectionBounds returns the corners of the selection, cut down to fit the map in case it has
+// been replaced or resized since, and false if nothing on the map is selected
+func selectionBounds(curMap *maps.Map) ([2]int, [2]int, bool) {
+	size := curMap.GetSize()
+	minPos := [2]int{maxInt(minInt(selection.start[0], selection.end[0]), 0), maxInt(minInt(selection.start[1], selection.end[1]), 0)}
+	maxPos := [2]int{minInt(maxInt(selection.start[0], selection.end[0]), size[0]-1), minInt(maxInt(selection.start[1], selection.end[1]), size[1]-1)}
+	return minPos, maxPos, selection.active && minPos[0] <= maxPos[0] && minPos[1] <= maxPos[1]
+}
+
+// copySelection puts the selected tiles on every layer on the clipboard and returns false if
+// nothing is selected
+func copySelection(curMap *maps.Map) bool {
+	minPos, maxPos, ok := selectionBounds(curMap)
+	if !ok {
+		return false
+	}
+	clipboard = curMap.CopyStamp(minPos, maxPos)
+	return true
+}
+
+// cutSelection copies the selected tiles and then clears them as one edit
+func cutSelection(curMap *maps.Map, edits *history) bool {
+	if !copySelection(curMap) {
+		return false
+	}
+	minPos, _, _ := selectionBounds(curMap)
+	placeStamp(curMap, edits, maps.NewStamp(clipboard.GetSize()), minPos)
+	return true
+}
+
+// placeStamp places stamp with its top left corner at pos as one edit
+func placeStamp(curMap *maps.Map, edits *history, stamp *maps.Stamp, pos [2]int) {
+	size := stamp.GetSize()
+	edits.begin()
+	for _, point := range rectPoints(pos, [2]int{pos[0] + size[0] - 1, pos[1] + size[1] - 1}, false) {
+		edits.record(point)
+	}
+	curMap.PlaceStamp(stamp, pos)
+	edits.end()
+}
+
+// drawClipboard outlines the selection and, while the select tool is active, where the clipboard
+// would be pasted
+func drawClipboard(curMap *maps.Map) {
+	if minPos, maxPos, ok := selectionBounds(curMap); ok {
+		drawOutline(minPos, maxPos, selectionColor)
+	}
+	if clipboard != nil && activeTool == selectTool && hoverOnMap {
+		size := clipboard.GetSize()
+		drawOutline(hoverPos, [2]int{hoverPos[0] + size[0] - 1, hoverPos[1] + size[1] - 1}, pasteColor)
+	}
+}
+
+// drawOutline draws a line around the edges of the tiles from minPos to maxPos
+func drawOutline(minPos [2]int, maxPos [2]int, color mgl32.Vec4) {
+	// the tiles cover half a unit past their centres
+	left, top := float32(minPos[0])-0.5, float32(minPos[1])-0.5
+	right, bottom := float32(maxPos[0])+0.5, float32(maxPos[1])+0.5
+	tiles.RenderRect(mgl32.Vec2{left, top}, mgl32.Vec2{right, top + guideWidth}, color)
+	tiles.RenderRect(mgl32.Vec2{left, bottom - guideWidth}, mgl32.Vec2{right, bottom}, color)
+	tiles.RenderRect(mgl32.Vec2{left, top}, mgl32.Vec2{left + guideWidth, bottom}, color)
+	tiles.RenderRect(mgl32.Vec2{right - guideWidth, top}, mgl32.Vec2{right, bottom}, color)
+}
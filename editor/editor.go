@@ -0,0 +1,442 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/sqweek/dialog"
+
+	"github.com/sunkink29/3dpacman/input"
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/rendering"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// autoLayer is the active layer when tiles are put on the layer their type belongs to
+const autoLayer maps.Layer = -1
+
+// the layer clicked tiles are put on
+var activeLayer = autoLayer
+
+// ctrlKey remembers if a key was pressed with Ctrl held so letting go of Ctrl before the key does
+// not also do what the key does on its own
+type ctrlKey bool
+
+// withCtrl returns true from when the key is pressed with Ctrl held until it is pressed without it
+func (pressed *ctrlKey) withCtrl(action glfw.Action, mods glfw.ModifierKey) bool {
+	if action == glfw.Press {
+		*pressed = mods&glfw.ModControl != 0
+	}
+	return bool(*pressed)
+}
+
+// the prompt asking for the size of a new or resized map and the list of stamps to pick from
+var (
+	mapSizePrompt *sizePrompt
+	stamps        *stampBrowser
+)
+
+// RegisterMapBindings registers the keys and mouse buttons used to edit, load and save maps.
+// Tiles are placed by clicking or dragging with the active tool and cleared with the right button.
+// Ctrl+Z undoes the last click or drag and Ctrl+Y or Ctrl+Shift+Z redoes it. Ctrl+N asks for the
+// size of a new blank map and Ctrl+R for the size to resize the map to. Ctrl+C, Ctrl+X and Ctrl+V
+// copy, cut and paste the rectangle picked with the select tool, Ctrl+S saves what was copied to
+// the stamp library and Ctrl+B lists the stamps in it. onMapChanged is called whenever curMap is
+// replaced or its game starts over. text.Init has to be called first.
+func RegisterMapBindings(curMap *maps.Map, tTile *tile.Tile, camera *rendering.Camera, onMapChanged func()) {
+	mapSizePrompt = newSizePrompt()
+	stamps = newStampBrowser()
+	edits := newHistory(curMap)
+	var curStroke stroke
+	input.RegisterKeyBinding(glfw.KeyW, "Toggle Up Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			tTile.Type = tile.Wall
+			tTile.Flags ^= tile.Up
+			if tTile.Flags&tile.All == 0 {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	var saveStampKey ctrlKey
+	input.RegisterKeyBinding(glfw.KeyS, "Toggle Down Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if saveStampKey.withCtrl(action, mods) {
+			if action == glfw.Release {
+				saveStamp()
+			}
+			return
+		}
+		if action == glfw.Release {
+			tTile.Type = tile.Wall
+			tTile.Flags ^= tile.Down
+			if tTile.Flags&tile.All == 0 {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyA, "Toggle Left Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			tTile.Type = tile.Wall
+			tTile.Flags ^= tile.Left
+			if tTile.Flags&tile.All == 0 {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyD, "Toggle Right Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			tTile.Type = tile.Wall
+			tTile.Flags ^= tile.Right
+			if tTile.Flags&tile.All == 0 {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	var resizeKey ctrlKey
+	input.RegisterKeyBinding(glfw.KeyR, "Toggle Auto Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if resizeKey.withCtrl(action, mods) {
+			if action == glfw.Release && !curStroke.active {
+				mapSizePrompt.show("Resize Map", curMap.GetSize(), true, func(size [2]int, anchor maps.Anchor) {
+					if err := curMap.Resize(size, anchor); err != nil {
+						fmt.Println(err)
+						return
+					}
+					edits.clear()
+					onMapChanged()
+				})
+			}
+			return
+		}
+		if action == glfw.Release {
+			if tTile.Type != tile.Wall {
+				tTile.Type = tile.Wall
+			} else {
+				if tTile.Flags&tile.All != 0 {
+					tTile.Flags &= 0xFFF0
+				} else {
+					tTile.Type = tile.Blank
+				}
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyE, "Toggle Dot Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			if tTile.Type != tile.Dot {
+				tTile.Type = tile.Dot
+			} else {
+				tTile.Type = tile.Blank
+			}
+
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyQ, "Toggle Big Dot Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			if tTile.Type != tile.DotBig {
+				tTile.Type = tile.DotBig
+			} else {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyT, "Toggle Player Spawn Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			if tTile.Type != tile.PlayerSpawn {
+				tTile.Type = tile.PlayerSpawn
+			} else {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	var redoKey ctrlKey
+	input.RegisterKeyBinding(glfw.KeyY, "Toggle Tunnel Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if redoKey.withCtrl(action, mods) {
+			if action != glfw.Release && !edits.redo() {
+				fmt.Println("Nothing to redo")
+			}
+			return
+		}
+		if action == glfw.Release {
+			if tTile.Type != tile.Tunnel {
+				tTile.Type = tile.Tunnel
+			} else {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyG, "Toggle Ghost House Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			if tTile.Type != tile.GhostHouse {
+				tTile.Type = tile.GhostHouse
+			} else {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyH, "Toggle Ghost Door Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			if tTile.Type != tile.GhostDoor {
+				tTile.Type = tile.GhostDoor
+			} else {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyU, "Toggle Fruit Spawn Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			if tTile.Type != tile.FruitSpawn {
+				tTile.Type = tile.FruitSpawn
+			} else {
+				tTile.Type = tile.Blank
+			}
+		}
+	})
+	var undoKey ctrlKey
+	input.RegisterKeyBinding(glfw.KeyZ, "Clear Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if undoKey.withCtrl(action, mods) {
+			if action == glfw.Release {
+				return
+			}
+			if mods&glfw.ModShift != 0 {
+				if !edits.redo() {
+					fmt.Println("Nothing to redo")
+				}
+			} else if !edits.undo() {
+				fmt.Println("Nothing to undo")
+			}
+			return
+		}
+		if action == glfw.Release {
+			tTile.Type = tile.Blank
+			tTile.Flags = 0x0
+		}
+	})
+	var cutKey ctrlKey
+	input.RegisterKeyBinding(glfw.KeyX, "Toggle WireFrame", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if cutKey.withCtrl(action, mods) {
+			if action == glfw.Press && !curStroke.active && !cutSelection(curMap, edits) {
+				fmt.Println("Nothing selected to cut")
+			}
+			return
+		}
+		if action == glfw.Release {
+			rendering.RenderWireframe ^= 1
+		}
+
+	})
+	var copyKey ctrlKey
+	input.RegisterKeyBinding(glfw.KeyC, "Load Map", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if copyKey.withCtrl(action, mods) {
+			if action == glfw.Press && !copySelection(curMap) {
+				fmt.Println("Nothing selected to copy")
+			}
+			return
+		}
+		if action == glfw.Release {
+			filename, err := dialog.File().Filter("Map files", "tmap", "amap", "json").Load()
+			if err != nil {
+				fmt.Println("Error getting map filename:", err)
+				return
+			}
+			newMap, err := maps.Load(filename)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			*curMap = *newMap
+			onMapChanged()
+		}
+	})
+	var pasteKey ctrlKey
+	input.RegisterKeyBinding(glfw.KeyV, "Save Map", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if pasteKey.withCtrl(action, mods) {
+			if action != glfw.Press || curStroke.active {
+				return
+			}
+			pos, ok := cursorTile(w, curMap, camera)
+			if clipboard == nil {
+				fmt.Println("Nothing copied to paste")
+			} else if !ok {
+				fmt.Println("Error pasting: the cursor is not over the map")
+			} else {
+				placeStamp(curMap, edits, clipboard, pos)
+			}
+			return
+		}
+		if action == glfw.Release {
+			filename, err := dialog.File().Filter("Map files", "tmap", "amap", "json").Title("Save Map").Save()
+			if err != nil {
+				fmt.Println("Error getting map filename:", err)
+				return
+			}
+			err = curMap.Save(filename)
+			if err != nil {
+				fmt.Println(err)
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyF, "Load Test Map", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			newMap, err := maps.LoadMapFromFile("assets/maps/smallTestMap.tmap")
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			*curMap = *newMap
+			onMapChanged()
+		}
+	})
+	input.RegisterKeyBinding(glfw.Key0, "Select Auto Layer", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			activeLayer = autoLayer
+			fmt.Println("Active layer: auto")
+		}
+	})
+	for layer := maps.Layer(0); layer < maps.NumLayers; layer++ {
+		layer := layer
+		input.RegisterKeyBinding(glfw.Key1+glfw.Key(layer), "Select "+layer.String()+" Layer", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+			if action == glfw.Release {
+				activeLayer = layer
+				fmt.Println("Active layer:", layer)
+			}
+		})
+	}
+	// ctrlAction is called instead of choosing the tool when Ctrl is held
+	registerTool := func(key glfw.Key, name string, newTool tool, ctrlAction func()) {
+		var toolKey ctrlKey
+		input.RegisterKeyBinding(key, "Select "+name+" Tool", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+			if ctrlAction != nil && toolKey.withCtrl(action, mods) {
+				if action == glfw.Release && !curStroke.active {
+					ctrlAction()
+				}
+				return
+			}
+			if action == glfw.Release {
+				// choosing the rectangle again switches between filled and hollow
+				if newTool == rectTool && activeTool == rectTool {
+					activeTool = hollowRectTool
+				} else {
+					activeTool = newTool
+				}
+				fmt.Println("Active tool:", activeTool)
+			}
+		})
+	}
+	registerTool(glfw.KeyP, "Paint", paintTool, nil)
+	registerTool(glfw.KeyO, "Rectangle", rectTool, nil)
+	registerTool(glfw.KeyN, "Line", lineTool, func() {
+		mapSizePrompt.show("New Map", curMap.GetSize(), false, func(size [2]int, anchor maps.Anchor) {
+			*curMap = maps.CreateEmptyMap(size)
+			edits.clear()
+			onMapChanged()
+		})
+	})
+	registerTool(glfw.KeyB, "Fill", fillTool, func() {
+		stamps.show(func(stamp *maps.Stamp, name string) {
+			clipboard = stamp
+			activeTool = selectTool
+			fmt.Println("Picked stamp", name, "press Ctrl+V to paste it")
+		})
+	})
+	registerTool(glfw.KeySlash, "Select", selectTool, nil)
+	input.RegisterKeyBinding(glfw.KeyComma, "Rotate Clipboard", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release && clipboard != nil {
+			clipboard = clipboard.RotateClockwise()
+			if mods&glfw.ModShift != 0 {
+				clipboard = clipboard.RotateClockwise()
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyPeriod, "Flip Clipboard", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release && clipboard != nil {
+			if mods&glfw.ModShift != 0 {
+				clipboard = clipboard.FlipUpDown()
+			} else {
+				clipboard = clipboard.FlipLeftRight()
+			}
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyLeftBracket, "Toggle Left Right Mirror", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			mirrorLeftRight = !mirrorLeftRight
+			fmt.Println("Mirror left to right:", mirrorLeftRight)
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyRightBracket, "Toggle Up Down Mirror", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			mirrorUpDown = !mirrorUpDown
+			fmt.Println("Mirror top to bottom:", mirrorUpDown)
+		}
+	})
+	input.RegisterMouseButtonBinding("map editor click", func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		if button != glfw.MouseButton1 && button != glfw.MouseButton2 {
+			return
+		}
+		if action == glfw.Release {
+			if curStroke.active && button == curStroke.button {
+				curStroke.endStroke(edits)
+			}
+			return
+		}
+		if IsPromptOpen() {
+			return
+		}
+		if pos, ok := cursorTile(w, curMap, camera); ok && !curStroke.active {
+			curStroke.startStroke(curMap, edits, button, pos, *tTile)
+		}
+	})
+	input.RegisterCursorPosBinding("map editor drag", func(w *glfw.Window, xpos float64, ypos float64) {
+		hoverPos, hoverOnMap = cursorTile(w, curMap, camera)
+		if curStroke.active && hoverOnMap {
+			curStroke.moveStroke(curMap, edits, hoverPos)
+		}
+	})
+}
+
+// Draw draws the editor guides and selection over the map and the map size prompt or stamp list
+// if one is open
+func Draw(curMap *maps.Map) {
+	drawMirrorGuides(curMap)
+	drawClipboard(curMap)
+	if mapSizePrompt != nil {
+		mapSizePrompt.draw()
+		stamps.draw()
+	}
+}
+
+// IsPromptOpen returns true while the editor is asking for the size of a map or for a stamp
+func IsPromptOpen() bool {
+	return mapSizePrompt != nil && (mapSizePrompt.open || stamps.open)
+}
+
+// saveStamp asks where to save what was copied and adds it to the stamp library, named after
+// the file it is saved to
+func saveStamp() {
+	if clipboard == nil {
+		fmt.Println("Nothing copied to save as a stamp")
+		return
+	}
+	filename, err := dialog.File().Filter("Stamp files", "json", "tmap", "amap").SetStartDir(maps.StampDir).Title("Save Stamp").Save()
+	if err != nil {
+		fmt.Println("Error getting stamp filename:", err)
+		return
+	}
+	if err := clipboard.Save(filename); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// paintTile puts a tile at pos on the active layer, or on the layer its type belongs to clearing
+// the others, recording the change in edits. It is also put on the positions pos is mirrored to.
+func paintTile(curMap *maps.Map, edits *history, pos [2]int, tileType tile.TileType, flags tile.TileFlag) {
+	if activeLayer != autoLayer && !activeLayer.Allows(tileType) {
+		fmt.Println("Error placing tile:", tileType, "can not be on the", activeLayer, "layer")
+		return
+	}
+	for _, place := range mirrorPlacements(curMap.GetSize(), pos, flags) {
+		edits.record(place.pos)
+		if activeLayer == autoLayer {
+			curMap.ChangeMapTile(place.pos, tileType, place.flags)
+		} else {
+			curMap.SetTile(activeLayer, place.pos, tileType, place.flags)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package editor
+
+import (
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// the most edits kept to be undone, the oldest are dropped after this
+const maxUndoSteps = 200
+
+// tileState is a position on the map with its tile on every layer
+type tileState [maps.NumLayers]tile.Tile
+
+func getTileState(curMap *maps.Map, pos [2]int) tileState {
+	var state tileState
+	for layer := range state {
+		state[layer] = curMap.GetLayerTile(maps.Layer(layer), pos)
+	}
+	return state
+}
+
+// setTileState puts back every layer of a position exactly as it was
+func setTileState(curMap *maps.Map, pos [2]int, state tileState) {
+	for layer, cTile := range state {
+		curMap.PlaceTile(maps.Layer(layer), pos, cTile.Type, cTile.Flags)
+	}
+}
+
+// tileChange is a position changed by an edit with its tiles before and after it
+type tileChange struct {
+	pos           [2]int
+	before, after tileState
+}
+
+// edit is a single undo step, every tile changed by one click or drag stroke
+type edit []tileChange
+
+// history records the edits made to a map in the editor so they can be undone and redone.
+// Tiles are recorded before they are changed along with the tiles next to them, which placing
+// walls also changes, and the edit keeps the ones that are different once it is finished.
+type history struct {
+	curMap *maps.Map
+	undos  []edit
+	redos  []edit
+	// the edit being recorded and the index of each of its positions
+	current   edit
+	recorded  map[[2]int]int
+	recording bool
+	// the map the edits were made to, they are dropped when another map is loaded
+	filename string
+	size     [2]int
+}
+
+func newHistory(curMap *maps.Map) *history {
+	return &history{curMap: curMap}
+}
+
+// checkMap drops every edit if a different map has been loaded since they were made
+func (edits *history) checkMap() {
+	if edits.curMap.GetFilename() != edits.filename || edits.curMap.GetSize() != edits.size {
+		edits.clear()
+	}
+}
+
+// clear drops every edit, for when the whole map is replaced or resized
+func (edits *history) clear() {
+	edits.undos = nil
+	edits.redos = nil
+	edits.filename = edits.curMap.GetFilename()
+	edits.size = edits.curMap.GetSize()
+}
+
+// begin starts a new edit, every tile changed until end is undone together
+func (edits *history) begin() {
+	edits.checkMap()
+	edits.current = nil
+	edits.recorded = make(map[[2]int]int)
+	edits.recording = true
+}
+
+// record saves the tile at pos and the tiles next to it before they are changed.
+// Tiles already recorded in the current edit are kept as they were at its start.
+func (edits *history) record(pos [2]int) {
+	if !edits.recording {
+		return
+	}
+	size := edits.curMap.GetSize()
+	for _, offset := range [5][2]int{{0, 0}, {0, -1}, {0, 1}, {-1, 0}, {1, 0}} {
+		next := [2]int{pos[0] + offset[0], pos[1] + offset[1]}
+		if next[0] < 0 || next[1] < 0 || next[0] >= size[0] || next[1] >= size[1] {
+			continue
+		}
+		if _, ok := edits.recorded[next]; !ok {
+			edits.recorded[next] = len(edits.current)
+			edits.current = append(edits.current, tileChange{pos: next, before: getTileState(edits.curMap, next)})
+		}
+	}
+}
+
+// revert puts back every tile changed so far in the current edit, which keeps recording
+func (edits *history) revert() {
+	for i := len(edits.current) - 1; i >= 0; i-- {
+		setTileState(edits.curMap, edits.current[i].pos, edits.current[i].before)
+	}
+}
+
+// end finishes the current edit and makes it the next one to undo if it changed anything
+func (edits *history) end() {
+	if !edits.recording {
+		return
+	}
+	edits.recording = false
+	var changed edit
+	for _, change := range edits.current {
+		change.after = getTileState(edits.curMap, change.pos)
+		if change.after != change.before {
+			changed = append(changed, change)
+		}
+	}
+	edits.current = nil
+	edits.recorded = nil
+	if len(changed) == 0 {
+		return
+	}
+	edits.undos = append(edits.undos, changed)
+	if len(edits.undos) > maxUndoSteps {
+		edits.undos = edits.undos[len(edits.undos)-maxUndoSteps:]
+	}
+	edits.redos = nil
+}
+
+// undo puts back the tiles changed by the last edit and returns false if there is nothing to undo
+func (edits *history) undo() bool {
+	edits.checkMap()
+	if edits.recording || len(edits.undos) == 0 {
+		return false
+	}
+	last := edits.undos[len(edits.undos)-1]
+	edits.undos = edits.undos[:len(edits.undos)-1]
+	for i := len(last) - 1; i >= 0; i-- {
+		setTileState(edits.curMap, last[i].pos, last[i].before)
+	}
+	edits.redos = append(edits.redos, last)
+	return true
+}
+
+// redo makes the last undone edit again and returns false if there is nothing to redo
+func (edits *history) redo() bool {
+	edits.checkMap()
+	if edits.recording || len(edits.redos) == 0 {
+		return false
+	}
+	last := edits.redos[len(edits.redos)-1]
+	edits.redos = edits.redos[:len(edits.redos)-1]
+	for _, change := range last {
+		setTileState(edits.curMap, change.pos, change.after)
+	}
+	edits.undos = append(edits.undos, last)
+	return true
+}
@@ -0,0 +1,62 @@
+package editor
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/rendering/tiles"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// when set every tile placed is also placed mirrored across the middle of the map, left to right
+// for mirrorLeftRight and top to bottom for mirrorUpDown
+var mirrorLeftRight, mirrorUpDown bool
+
+// the width of the mirror axis guides and their color
+const guideWidth = 0.1
+
+var guideColor = mgl32.Vec4{1, 0, 1, 1}
+
+// placement is a position a tile is placed on with the flags it gets there
+type placement struct {
+	pos   [2]int
+	flags tile.TileFlag
+}
+
+// mirrorPlacements returns pos with flags followed by each position it is mirrored to with the wall
+// directions flipped to match. Tiles on an axis are only placed once.
+func mirrorPlacements(size [2]int, pos [2]int, flags tile.TileFlag) []placement {
+	placements := []placement{{pos, flags}}
+	add := func(mirrored placement) {
+		for _, existing := range placements {
+			if existing.pos == mirrored.pos {
+				return
+			}
+		}
+		placements = append(placements, mirrored)
+	}
+	if mirrorLeftRight {
+		add(placement{[2]int{size[0] - 1 - pos[0], pos[1]}, flags.FlipLeftRight()})
+	}
+	if mirrorUpDown {
+		for _, existing := range placements {
+			add(placement{[2]int{existing.pos[0], size[1] - 1 - existing.pos[1]}, existing.flags.FlipUpDown()})
+		}
+	}
+	return placements
+}
+
+// drawMirrorGuides draws a line along each axis tiles are being mirrored across
+func drawMirrorGuides(curMap *maps.Map) {
+	size := curMap.GetSize()
+	// the map covers half a unit past the centres of the tiles on its edges
+	minPos := mgl32.Vec2{-0.5, -0.5}
+	maxPos := mgl32.Vec2{float32(size[0]) - 0.5, float32(size[1]) - 0.5}
+	center := minPos.Add(maxPos).Mul(0.5)
+	if mirrorLeftRight {
+		tiles.RenderRect(mgl32.Vec2{center[0] - guideWidth/2, minPos[1]}, mgl32.Vec2{center[0] + guideWidth/2, maxPos[1]}, guideColor)
+	}
+	if mirrorUpDown {
+		tiles.RenderRect(mgl32.Vec2{minPos[0], center[1] - guideWidth/2}, mgl32.Vec2{maxPos[0], center[1] + guideWidth/2}, guideColor)
+	}
+}
@@ -0,0 +1,182 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+
+	v41 "github.com/4ydx/gltext/v4.1"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/sunkink29/3dpacman/input"
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/rendering/text"
+)
+
+// the most digits that can be typed for a width or height
+const maxSizeDigits = 4
+
+const (
+	promptFont       = "8bitmadness"
+	promptFontSize   = 30
+	promptLineHeight = 40
+)
+
+var (
+	promptColor      = mgl32.Vec3{1, 1, 1}
+	promptFieldColor = mgl32.Vec3{1, 1, 0}
+	promptErrorColor = mgl32.Vec3{1, 0, 0}
+)
+
+// the fields of the prompt, the anchor is only shown when resizing
+const (
+	widthField = iota
+	heightField
+	anchorField
+)
+
+// sizePrompt asks for the width and height of a map and, when resizing, the side or corner of the
+// map that stays in place. It takes every key while it is open: digits and Backspace type the
+// size, Tab moves between the fields, the arrow keys move the anchor, Enter accepts and Esc cancels.
+type sizePrompt struct {
+	open   bool
+	resize bool
+	sizes  [2]string
+	field  int
+	anchor maps.Anchor
+	onDone func(size [2]int, anchor maps.Anchor)
+	lines  [5]*v41.Text
+}
+
+// newSizePrompt creates a closed prompt, text.Init has to be called first
+func newSizePrompt() *sizePrompt {
+	font := text.GetFont(promptFont, promptFontSize)
+	prompt := &sizePrompt{}
+	for i := range prompt.lines {
+		prompt.lines[i] = text.New(" ", font, mgl32.Vec2{0, float32(100 - i*promptLineHeight)}, promptColor)
+	}
+	return prompt
+}
+
+// show opens the prompt with title starting at size. onDone is called with the size and anchor
+// once they are accepted.
+func (prompt *sizePrompt) show(title string, size [2]int, resize bool, onDone func(size [2]int, anchor maps.Anchor)) {
+	prompt.open = true
+	prompt.resize = resize
+	prompt.sizes = [2]string{strconv.Itoa(size[0]), strconv.Itoa(size[1])}
+	prompt.field = widthField
+	prompt.anchor = maps.Center
+	prompt.onDone = onDone
+	prompt.lines[0].SetString("%s", title)
+	prompt.update("Enter to accept, Esc to cancel", promptColor)
+	input.CaptureKeys(prompt.onKey)
+}
+
+func (prompt *sizePrompt) close() {
+	prompt.open = false
+	input.ReleaseKeys()
+}
+
+// onKey handles every key while the prompt is open. Enter and Esc act when they are let go so
+// their key bindings do not see the release once the keys are given back.
+func (prompt *sizePrompt) onKey(w *glfw.Window, key glfw.Key, action glfw.Action, mods glfw.ModifierKey) {
+	if action == glfw.Release {
+		switch key {
+		case glfw.KeyEnter, glfw.KeyKPEnter:
+			prompt.accept()
+		case glfw.KeyEscape:
+			prompt.close()
+		}
+		return
+	}
+	numFields := 2
+	if prompt.resize {
+		numFields = 3
+	}
+	switch {
+	case key >= glfw.Key0 && key <= glfw.Key9, key >= glfw.KeyKP0 && key <= glfw.KeyKP9:
+		digit := int(key - glfw.Key0)
+		if key >= glfw.KeyKP0 {
+			digit = int(key - glfw.KeyKP0)
+		}
+		if prompt.field != anchorField && len(prompt.sizes[prompt.field]) < maxSizeDigits {
+			prompt.sizes[prompt.field] += strconv.Itoa(digit)
+		}
+	case key == glfw.KeyBackspace:
+		if prompt.field != anchorField && len(prompt.sizes[prompt.field]) > 0 {
+			prompt.sizes[prompt.field] = prompt.sizes[prompt.field][:len(prompt.sizes[prompt.field])-1]
+		}
+	case key == glfw.KeyTab:
+		if mods&glfw.ModShift != 0 {
+			prompt.field = (prompt.field + numFields - 1) % numFields
+		} else {
+			prompt.field = (prompt.field + 1) % numFields
+		}
+	case prompt.field == anchorField:
+		// the anchors are laid out in a 3 by 3 grid from the top left
+		col, row := int(prompt.anchor)%3, int(prompt.anchor)/3
+		switch key {
+		case glfw.KeyLeft:
+			col = maxInt(col-1, 0)
+		case glfw.KeyRight:
+			col = minInt(col+1, 2)
+		case glfw.KeyUp:
+			row = maxInt(row-1, 0)
+		case glfw.KeyDown:
+			row = minInt(row+1, 2)
+		}
+		prompt.anchor = maps.Anchor(row*3 + col)
+	}
+	prompt.update("Enter to accept, Esc to cancel", promptColor)
+}
+
+// accept closes the prompt and calls onDone if the size is valid, otherwise the error is shown
+func (prompt *sizePrompt) accept() {
+	var size [2]int
+	for i, str := range prompt.sizes {
+		value, err := strconv.Atoi(str)
+		if err != nil || value < 1 || value > maps.MaxMapSize {
+			prompt.update(fmt.Sprint("Size must be 1 to ", maps.MaxMapSize), promptErrorColor)
+			return
+		}
+		size[i] = value
+	}
+	prompt.close()
+	prompt.onDone(size, prompt.anchor)
+}
+
+// update sets the text of the fields, marking the one being typed in, and the message under them
+func (prompt *sizePrompt) update(message string, messageColor mgl32.Vec3) {
+	fields := []string{"Width " + prompt.sizes[0], "Height " + prompt.sizes[1]}
+	if prompt.resize {
+		fields = append(fields, "Keep "+prompt.anchor.String())
+	}
+	for i, line := range prompt.lines[1 : len(prompt.lines)-1] {
+		if i >= len(fields) {
+			line.Hide()
+			continue
+		}
+		str := fields[i]
+		if i == prompt.field {
+			str += "_"
+			line.SetColor(promptFieldColor)
+		} else {
+			line.SetColor(promptColor)
+		}
+		line.SetString("%s", str)
+		line.Show()
+	}
+	messageLine := prompt.lines[len(prompt.lines)-1]
+	messageLine.SetString("%s", message)
+	messageLine.SetColor(messageColor)
+}
+
+// draw draws the prompt if it is open
+func (prompt *sizePrompt) draw() {
+	if !prompt.open {
+		return
+	}
+	for _, line := range prompt.lines {
+		line.Draw()
+	}
+}
@@ -0,0 +1,155 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	v41 "github.com/4ydx/gltext/v4.1"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/sunkink29/3dpacman/input"
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/rendering/text"
+)
+
+// the number of stamps listed at once
+const visibleStamps = 10
+
+// stampBrowser lists the stamps in the library so one can be picked to paste. It takes every key
+// while it is open: Tab, Shift+Tab and the up and down arrows choose a stamp, Enter picks it and
+// Esc closes the list.
+type stampBrowser struct {
+	open      bool
+	filenames []string
+	selected  int
+	onPick    func(stamp *maps.Stamp, name string)
+	heading   *v41.Text
+	list      []*v41.Text
+}
+
+// newStampBrowser creates a closed browser, text.Init has to be called first
+func newStampBrowser() *stampBrowser {
+	font := text.GetFont(promptFont, promptFontSize)
+	browser := &stampBrowser{
+		heading: text.New("Stamps", font, mgl32.Vec2{0, 220}, promptFieldColor),
+		list:    make([]*v41.Text, visibleStamps),
+	}
+	for i := range browser.list {
+		browser.list[i] = text.New(" ", font, mgl32.Vec2{0, float32(160 - i*promptLineHeight)}, promptColor)
+	}
+	return browser
+}
+
+// stampName returns the name of the stamp in filename, the file name without its extension
+func stampName(filename string) string {
+	return strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+}
+
+// show lists the stamps in maps.StampDir. onPick is called with the stamp that is picked.
+func (browser *stampBrowser) show(onPick func(stamp *maps.Stamp, name string)) {
+	browser.filenames = browser.filenames[:0]
+	for _, ext := range []string{".tmap", maps.TextExtension, maps.JSONExtension} {
+		matches, _ := filepath.Glob(filepath.Join(maps.StampDir, "*"+ext))
+		browser.filenames = append(browser.filenames, matches...)
+	}
+	sort.Strings(browser.filenames)
+	if browser.selected >= len(browser.filenames) {
+		browser.selected = 0
+	}
+	browser.onPick = onPick
+	browser.open = true
+	browser.update()
+	input.CaptureKeys(browser.onKey)
+}
+
+func (browser *stampBrowser) close() {
+	browser.open = false
+	input.ReleaseKeys()
+}
+
+// onKey handles every key while the browser is open, Enter and Esc act when they are let go for
+// the same reason as in sizePrompt.onKey
+func (browser *stampBrowser) onKey(w *glfw.Window, key glfw.Key, action glfw.Action, mods glfw.ModifierKey) {
+	if action == glfw.Release {
+		switch key {
+		case glfw.KeyEnter, glfw.KeyKPEnter:
+			browser.pick()
+		case glfw.KeyEscape:
+			browser.close()
+		}
+		return
+	}
+	if len(browser.filenames) == 0 {
+		return
+	}
+	previous := key == glfw.KeyUp || key == glfw.KeyTab && mods&glfw.ModShift != 0
+	switch {
+	case previous:
+		browser.selected = (browser.selected + len(browser.filenames) - 1) % len(browser.filenames)
+	case key == glfw.KeyDown, key == glfw.KeyTab:
+		browser.selected = (browser.selected + 1) % len(browser.filenames)
+	}
+	browser.update()
+}
+
+// pick loads the selected stamp and closes the browser, it stays open if the stamp can not be loaded
+func (browser *stampBrowser) pick() {
+	if len(browser.filenames) == 0 {
+		browser.close()
+		return
+	}
+	filename := browser.filenames[browser.selected]
+	stamp, err := maps.LoadStamp(filename)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	browser.close()
+	browser.onPick(stamp, stampName(filename))
+}
+
+// update sets the text of every line to show the selected stamp
+func (browser *stampBrowser) update() {
+	if len(browser.filenames) == 0 {
+		browser.list[0].SetString("%s", "No stamps in "+maps.StampDir)
+		browser.list[0].SetColor(promptColor)
+		browser.list[0].Show()
+		for _, line := range browser.list[1:] {
+			line.Hide()
+		}
+		return
+	}
+	// scroll the list so the selected stamp is always shown
+	first := 0
+	if browser.selected >= visibleStamps {
+		first = browser.selected - visibleStamps + 1
+	}
+	for i, line := range browser.list {
+		index := first + i
+		if index >= len(browser.filenames) {
+			line.Hide()
+			continue
+		}
+		line.SetString("%s", stampName(browser.filenames[index]))
+		if index == browser.selected {
+			line.SetColor(promptFieldColor)
+		} else {
+			line.SetColor(promptColor)
+		}
+		line.Show()
+	}
+}
+
+// draw draws the browser if it is open
+func (browser *stampBrowser) draw() {
+	if !browser.open {
+		return
+	}
+	browser.heading.Draw()
+	for _, line := range browser.list {
+		line.Draw()
+	}
+}
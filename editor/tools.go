@@ -0,0 +1,227 @@
+package editor
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/rendering"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// tool is how dragging the mouse over the map places tiles
+type tool int
+
+const (
+	// paintTool places a tile on every tile the cursor is dragged over
+	paintTool tool = iota
+	// rectTool fills the rectangle between where the drag started and the cursor
+	rectTool
+	// hollowRectTool places the outline of the rectangle between where the drag started and the cursor
+	hollowRectTool
+	// lineTool places a straight line from where the drag started to the cursor
+	lineTool
+	// fillTool replaces the area of matching tiles that was clicked on
+	fillTool
+	// selectTool picks the rectangle between where the drag started and the cursor to copy or cut
+	selectTool
+)
+
+var toolNames = [...]string{"paint", "rectangle", "hollow rectangle", "line", "fill", "select"}
+
+func (curTool tool) String() string {
+	return toolNames[curTool]
+}
+
+// the tool used when a mouse button is pressed on the map
+var activeTool = paintTool
+
+// stroke is a drag with a mouse button held down. Everything it places is undone together.
+type stroke struct {
+	active   bool
+	button   glfw.MouseButton
+	start    [2]int
+	last     [2]int
+	tileType tile.TileType
+	flags    tile.TileFlag
+}
+
+// cursorTile returns the tile on the map under the cursor and false if the cursor is off the map
+func cursorTile(w *glfw.Window, curMap *maps.Map, camera *rendering.Camera) ([2]int, bool) {
+	mouseX, mouseY := w.GetCursorPos()
+	matProjection := camera.ProjectionMatrix.Mul4(*camera.ViewMatrix).Inv()
+	worldPointf := rendering.ScreenToWorldSpace(w, [2]float64{mouseX, mouseY}, matProjection)
+	pos := [2]int{int(math.Floor(float64(worldPointf[0] + 0.5))), int(math.Floor(float64(worldPointf[2] + 0.5)))}
+	size := curMap.GetSize()
+	return pos, pos[0] >= 0 && pos[1] >= 0 && pos[0] < size[0] && pos[1] < size[1]
+}
+
+// startStroke begins placing tiles with the active tool when a mouse button is pressed on pos
+func (curStroke *stroke) startStroke(curMap *maps.Map, edits *history, button glfw.MouseButton, pos [2]int, tTile tile.Tile) {
+	*curStroke = stroke{true, button, pos, pos, tTile.Type, tTile.Flags}
+	if activeTool == selectTool {
+		// the right button clears the selection
+		selection.active = button == glfw.MouseButton1
+		selection.start, selection.end = pos, pos
+		curStroke.active = selection.active
+		return
+	}
+	if button == glfw.MouseButton2 {
+		curStroke.tileType = tile.Blank
+		curStroke.flags = 0x0
+	}
+	if activeLayer != autoLayer && !activeLayer.Allows(curStroke.tileType) {
+		fmt.Println("Error placing tile:", curStroke.tileType, "can not be on the", activeLayer, "layer")
+		curStroke.active = false
+		return
+	}
+	edits.begin()
+	if activeTool == fillTool {
+		for _, fillPos := range fillArea(curMap, pos) {
+			paintTile(curMap, edits, fillPos, curStroke.tileType, curStroke.flags)
+		}
+		curStroke.endStroke(edits)
+		return
+	}
+	curStroke.paint(curMap, edits, pos)
+}
+
+// moveStroke places tiles as the cursor is dragged onto pos. The rectangle and line tools take back
+// what they placed for the last cursor position first so the shape follows the cursor and the
+// select tool moves the corner of the selection instead.
+func (curStroke *stroke) moveStroke(curMap *maps.Map, edits *history, pos [2]int) {
+	if !curStroke.active || pos == curStroke.last {
+		return
+	}
+	if activeTool == selectTool {
+		selection.end = pos
+	} else if activeTool == paintTool {
+		// fill in the tiles skipped when the cursor moves more than one tile at a time
+		for _, linePos := range linePoints(curStroke.last, pos) {
+			curStroke.paint(curMap, edits, linePos)
+		}
+	} else {
+		edits.revert()
+		curStroke.paint(curMap, edits, pos)
+	}
+	curStroke.last = pos
+}
+
+// endStroke finishes the stroke when its mouse button is let go
+func (curStroke *stroke) endStroke(edits *history) {
+	curStroke.active = false
+	edits.end()
+}
+
+// paint places the tiles of the active tool for the cursor at pos
+func (curStroke *stroke) paint(curMap *maps.Map, edits *history, pos [2]int) {
+	var points [][2]int
+	switch activeTool {
+	case paintTool:
+		points = [][2]int{pos}
+	case rectTool, hollowRectTool:
+		points = rectPoints(curStroke.start, pos, activeTool == hollowRectTool)
+	case lineTool:
+		points = linePoints(curStroke.start, pos)
+	}
+	for _, point := range points {
+		paintTile(curMap, edits, point, curStroke.tileType, curStroke.flags)
+	}
+}
+
+// rectPoints returns the tiles in the rectangle with corners a and b, only its edges if hollow is true
+func rectPoints(a [2]int, b [2]int, hollow bool) [][2]int {
+	minPos := [2]int{minInt(a[0], b[0]), minInt(a[1], b[1])}
+	maxPos := [2]int{maxInt(a[0], b[0]), maxInt(a[1], b[1])}
+	var points [][2]int
+	for x := minPos[0]; x <= maxPos[0]; x++ {
+		for y := minPos[1]; y <= maxPos[1]; y++ {
+			onEdge := x == minPos[0] || x == maxPos[0] || y == minPos[1] || y == maxPos[1]
+			if !hollow || onEdge {
+				points = append(points, [2]int{x, y})
+			}
+		}
+	}
+	return points
+}
+
+// linePoints returns the tiles on the straight line from a to b, including both ends
+func linePoints(a [2]int, b [2]int) [][2]int {
+	// Bresenham's line algorithm
+	dx, dy := absInt(b[0]-a[0]), -absInt(b[1]-a[1])
+	step := [2]int{1, 1}
+	if a[0] > b[0] {
+		step[0] = -1
+	}
+	if a[1] > b[1] {
+		step[1] = -1
+	}
+	points := [][2]int{a}
+	pos := a
+	err := dx + dy
+	for pos != b {
+		doubleErr := 2 * err
+		if doubleErr >= dy {
+			err += dy
+			pos[0] += step[0]
+		}
+		if doubleErr <= dx {
+			err += dx
+			pos[1] += step[1]
+		}
+		points = append(points, pos)
+	}
+	return points
+}
+
+// fillArea returns the tiles connected to start, without going diagonally, that match it on the
+// active layer or that the game sees as the same when every layer is being edited
+func fillArea(curMap *maps.Map, start [2]int) [][2]int {
+	getTile := func(pos [2]int) tile.Tile {
+		if activeLayer == autoLayer {
+			return curMap.GetMapTile(pos)
+		}
+		return curMap.GetLayerTile(activeLayer, pos)
+	}
+	match := getTile(start)
+	size := curMap.GetSize()
+	visited := make(map[[2]int]bool)
+	visited[start] = true
+	area := [][2]int{start}
+	for i := 0; i < len(area); i++ {
+		for _, dir := range [4][2]int{{0, -1}, {0, 1}, {-1, 0}, {1, 0}} {
+			next := [2]int{area[i][0] + dir[0], area[i][1] + dir[1]}
+			if next[0] < 0 || next[1] < 0 || next[0] >= size[0] || next[1] >= size[1] || visited[next] {
+				continue
+			}
+			visited[next] = true
+			if getTile(next).Type == match.Type {
+				area = append(area, next)
+			}
+		}
+	}
+	return area
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
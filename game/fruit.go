@@ -0,0 +1,47 @@
+package game
+
+// FruitSpawner follows the fruit schedule of a level, showing a fruit each time the player
+// has eaten enough dots and removing it again when its time runs out
+type FruitSpawner struct {
+	level     Level
+	dotsEaten int
+	next      int
+	timeLeft  float64
+}
+
+func NewFruitSpawner(level Level) FruitSpawner {
+	return FruitSpawner{level: level}
+}
+
+// DotEaten counts a dot and returns true if it made a fruit appear
+func (spawner *FruitSpawner) DotEaten() bool {
+	spawner.dotsEaten++
+	if spawner.next >= len(spawner.level.FruitDots) || spawner.dotsEaten < spawner.level.FruitDots[spawner.next] {
+		return false
+	}
+	spawner.next++
+	spawner.timeLeft = spawner.level.FruitTime
+	return true
+}
+
+// Update counts down the time until the current fruit disappears
+func (spawner *FruitSpawner) Update(deltaTime float64) {
+	if spawner.timeLeft > 0 {
+		spawner.timeLeft -= deltaTime
+	}
+}
+
+func (spawner *FruitSpawner) IsActive() bool {
+	return spawner.timeLeft > 0
+}
+
+// Despawn removes the current fruit without it being eaten
+func (spawner *FruitSpawner) Despawn() {
+	spawner.timeLeft = 0
+}
+
+// Eat removes the current fruit and returns which fruit it was and how many points it is worth
+func (spawner *FruitSpawner) Eat() (Fruit, int) {
+	spawner.timeLeft = 0
+	return spawner.level.Fruit, spawner.level.FruitScore
+}
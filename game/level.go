@@ -0,0 +1,87 @@
+package game
+
+// speeds in the level table are given as a fraction of this speed in tiles per second
+const baseSpeed = 6.25
+
+type Fruit int
+
+const (
+	Cherry Fruit = iota
+	Strawberry
+	Orange
+	Apple
+	Melon
+	Galaxian
+	Bell
+	Key
+)
+
+// Level holds the settings that change as the player clears levels
+type Level struct {
+	PlayerSpeed          float64 // tiles per second
+	GhostSpeed           float64 // tiles per second
+	GhostTunnelSpeed     float64 // tiles per second while a ghost is in a tunnel
+	FrightenedGhostSpeed float64 // tiles per second while a ghost is frightened
+	FrightenedTime       float64 // seconds, 0 means big dots do not frighten the ghosts
+	// durations in seconds of the scatter and chase phases. They alternate starting with scatter
+	// and the last chase phase lasts for the rest of the level
+	Phases     []float64
+	Fruit      Fruit
+	FruitScore int
+	// a fruit appears each time the number of dots eaten in the level reaches one of these counts
+	FruitDots []int
+	// seconds a fruit stays before it disappears
+	FruitTime float64
+	// how many dots each ghost counts in the ghost house before it leaves, indexed blinky, pinky, inky, clyde
+	GhostDotLimits [4]int
+	// seconds without a dot being eaten before the next ghost is released from the ghost house
+	GhostReleaseTime float64
+}
+
+var (
+	phasesLevel1 = []float64{7, 20, 7, 20, 5, 20, 5}
+	phasesLevel2 = []float64{7, 20, 7, 20, 5, 1033, 1.0 / 60}
+	phasesLevel5 = []float64{5, 20, 5, 20, 5, 1037, 1.0 / 60}
+
+	dotLimitsLevel1 = [4]int{0, 0, 30, 60}
+	dotLimitsLevel2 = [4]int{0, 0, 0, 50}
+	dotLimitsLevel3 = [4]int{0, 0, 0, 0}
+
+	arcadeFruitDots = []int{70, 170}
+)
+
+// Levels is the arcade level table. Levels past the end of the table use the last entry.
+var Levels = []Level{
+	{0.80 * baseSpeed, 0.75 * baseSpeed, 0.40 * baseSpeed, 0.50 * baseSpeed, 6, phasesLevel1, Cherry, 100, arcadeFruitDots, 9.5, dotLimitsLevel1, 4},
+	{0.90 * baseSpeed, 0.85 * baseSpeed, 0.45 * baseSpeed, 0.55 * baseSpeed, 5, phasesLevel2, Strawberry, 300, arcadeFruitDots, 9.5, dotLimitsLevel2, 4},
+	{0.90 * baseSpeed, 0.85 * baseSpeed, 0.45 * baseSpeed, 0.55 * baseSpeed, 4, phasesLevel2, Orange, 500, arcadeFruitDots, 9.5, dotLimitsLevel3, 4},
+	{0.90 * baseSpeed, 0.85 * baseSpeed, 0.45 * baseSpeed, 0.55 * baseSpeed, 3, phasesLevel2, Orange, 500, arcadeFruitDots, 9.5, dotLimitsLevel3, 4},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 2, phasesLevel5, Apple, 700, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 5, phasesLevel5, Apple, 700, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 2, phasesLevel5, Melon, 1000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 2, phasesLevel5, Melon, 1000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 1, phasesLevel5, Galaxian, 2000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 5, phasesLevel5, Galaxian, 2000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 2, phasesLevel5, Bell, 3000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 1, phasesLevel5, Bell, 3000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 1, phasesLevel5, Key, 5000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 3, phasesLevel5, Key, 5000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 1, phasesLevel5, Key, 5000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 1, phasesLevel5, Key, 5000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 0, phasesLevel5, Key, 5000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 1, phasesLevel5, Key, 5000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 0, phasesLevel5, Key, 5000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{1.00 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 0, phasesLevel5, Key, 5000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+	{0.90 * baseSpeed, 0.95 * baseSpeed, 0.50 * baseSpeed, 0.60 * baseSpeed, 0, phasesLevel5, Key, 5000, arcadeFruitDots, 9.5, dotLimitsLevel3, 3},
+}
+
+// GetLevel returns the settings for a level starting from level 1
+func GetLevel(level int) Level {
+	if level < 1 {
+		level = 1
+	}
+	if level > len(Levels) {
+		level = len(Levels)
+	}
+	return Levels[level-1]
+}
@@ -0,0 +1,184 @@
+package game
+
+// the simulation advances in fixed steps of StepTime seconds so it plays out the same way every time
+const (
+	TickRate = 60
+	StepTime = 1.0 / TickRate
+)
+
+const (
+	DotScore    = 10
+	BigDotScore = 50
+	// the first ghost eaten during a frightened phase is worth this much and each one after that doubles it
+	GhostScore = 200
+
+	StartLives            = 3
+	DefaultExtraLifeScore = 10000
+	// seconds the death state lasts before the player respawns
+	DeathTime = 1.5
+)
+
+type EventType int
+
+const (
+	DotEaten EventType = iota
+	BigDotEaten
+	LevelCleared
+	GhostEaten
+	PlayerDied
+	PlayerRespawned
+	ExtraLife
+	GameOver
+	LevelStarted
+	FruitSpawned
+	FruitEaten
+)
+
+// Phase is the state of the player in the game
+type Phase int
+
+const (
+	Playing Phase = iota
+	Dying
+	Over
+)
+
+// Event records something that happened during an update so the hud and sounds can react to it
+type Event struct {
+	Type   EventType
+	Pos    [2]int
+	Points int
+}
+
+// State holds the score and progress of the current game. It has no rendering dependencies
+// so the rules can be driven without a window.
+type State struct {
+	Score    int
+	DotsLeft int
+	Lives    int
+	Level    int
+	// an extra life is awarded the first time the score reaches ExtraLifeScore. 0 disables it
+	ExtraLifeScore int
+
+	phase            Phase
+	phaseTime        float64
+	extraLifeAwarded bool
+	// set when the last dot of the level is eaten, a level that started without dots is never cleared
+	levelCleared bool
+	ghostCombo   int
+	events       []Event
+}
+
+func NewState(dotCount int) State {
+	return State{DotsLeft: dotCount, Lives: StartLives, Level: 1, ExtraLifeScore: DefaultExtraLifeScore}
+}
+
+// NextLevel advances to the next level which starts with dotCount dots
+func (state *State) NextLevel(dotCount int) {
+	state.Level++
+	state.DotsLeft = dotCount
+	state.levelCleared = false
+	state.events = append(state.events, Event{Type: LevelStarted})
+}
+
+// EatDot awards the points for the dot at pos and returns true when it was the last dot of the level
+func (state *State) EatDot(pos [2]int, big bool) bool {
+	event := Event{DotEaten, pos, DotScore}
+	if big {
+		event = Event{BigDotEaten, pos, BigDotScore}
+	}
+	state.AddScore(event.Points)
+	state.events = append(state.events, event)
+
+	if state.DotsLeft > 0 {
+		state.DotsLeft--
+		if state.DotsLeft == 0 {
+			state.levelCleared = true
+			state.events = append(state.events, Event{LevelCleared, pos, 0})
+			return true
+		}
+	}
+	return false
+}
+
+func (state *State) AddScore(points int) {
+	state.Score += points
+	if !state.extraLifeAwarded && state.ExtraLifeScore > 0 && state.Score >= state.ExtraLifeScore {
+		state.extraLifeAwarded = true
+		state.Lives++
+		state.events = append(state.events, Event{Type: ExtraLife})
+	}
+}
+
+// ResetGhostCombo restarts the ghost score doubling at the start of a frightened phase
+func (state *State) ResetGhostCombo() {
+	state.ghostCombo = 0
+}
+
+// EatGhost awards the points for a frightened ghost eaten at pos
+func (state *State) EatGhost(pos [2]int) {
+	points := GhostScore << uint(state.ghostCombo)
+	if state.ghostCombo < 3 {
+		state.ghostCombo++
+	}
+	state.AddScore(points)
+	state.events = append(state.events, Event{GhostEaten, pos, points})
+}
+
+// FruitSpawned reports that a fruit appeared at pos
+func (state *State) FruitSpawned(pos [2]int) {
+	state.events = append(state.events, Event{Type: FruitSpawned, Pos: pos})
+}
+
+// EatFruit awards the points for a fruit eaten at pos
+func (state *State) EatFruit(pos [2]int, points int) {
+	state.AddScore(points)
+	state.events = append(state.events, Event{FruitEaten, pos, points})
+}
+
+// Kill costs the player a life and starts the death state
+func (state *State) Kill(pos [2]int) {
+	if state.phase != Playing {
+		return
+	}
+	state.Lives--
+	state.phase = Dying
+	state.phaseTime = DeathTime
+	state.events = append(state.events, Event{Type: PlayerDied, Pos: pos})
+}
+
+// Update advances the death state and returns true when the player and ghosts need to be reset to their spawns
+func (state *State) Update(deltaTime float64) bool {
+	if state.phase != Dying {
+		return false
+	}
+	state.phaseTime -= deltaTime
+	if state.phaseTime > 0 {
+		return false
+	}
+	state.phaseTime = 0
+	if state.Lives <= 0 {
+		state.phase = Over
+		state.events = append(state.events, Event{Type: GameOver})
+		return false
+	}
+	state.phase = Playing
+	state.events = append(state.events, Event{Type: PlayerRespawned})
+	return true
+}
+
+func (state *State) GetPhase() Phase {
+	return state.phase
+}
+
+// IsLevelCleared returns true once the last dot of the level has been eaten until the next level starts
+func (state *State) IsLevelCleared() bool {
+	return state.levelCleared
+}
+
+// PollEvents returns every event since the last call and clears the queue
+func (state *State) PollEvents() []Event {
+	events := state.events
+	state.events = nil
+	return events
+}
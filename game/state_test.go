@@ -0,0 +1,148 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEatDot(t *testing.T) {
+	state := NewState(3)
+	if state.EatDot([2]int{1, 1}, false) {
+		t.Error("the first of three dots cleared the level")
+	}
+	if state.EatDot([2]int{2, 1}, true) {
+		t.Error("the second of three dots cleared the level")
+	}
+	if state.Score != DotScore+BigDotScore || state.DotsLeft != 1 {
+		t.Fatalf("score is %d with %d dots left, want %d and 1", state.Score, state.DotsLeft, DotScore+BigDotScore)
+	}
+	if !state.EatDot([2]int{3, 1}, false) || !state.IsLevelCleared() {
+		t.Error("the last dot did not clear the level")
+	}
+	want := []Event{
+		{DotEaten, [2]int{1, 1}, DotScore},
+		{BigDotEaten, [2]int{2, 1}, BigDotScore},
+		{DotEaten, [2]int{3, 1}, DotScore},
+		{LevelCleared, [2]int{3, 1}, 0},
+	}
+	if got := state.PollEvents(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events are %v, want %v", got, want)
+	}
+	if events := state.PollEvents(); len(events) != 0 {
+		t.Errorf("events are %v after polling them, want none", events)
+	}
+
+	// dots that come back after the level is cleared, such as placed in the editor, do not clear it again
+	if state.EatDot([2]int{4, 1}, false) {
+		t.Error("a dot eaten after the level was cleared cleared it again")
+	}
+
+	state.NextLevel(2)
+	if state.Level != 2 || state.DotsLeft != 2 || state.IsLevelCleared() {
+		t.Errorf("next level is %d with %d dots, cleared %v, want 2 with 2 dots not cleared", state.Level, state.DotsLeft, state.IsLevelCleared())
+	}
+	if got, want := state.PollEvents(), []Event{{DotEaten, [2]int{4, 1}, DotScore}, {Type: LevelStarted}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events are %v, want %v", got, want)
+	}
+}
+
+func TestEatDotEmptyLevel(t *testing.T) {
+	state := NewState(0)
+	if state.EatDot([2]int{0, 0}, false) || state.IsLevelCleared() {
+		t.Error("a level that started without dots was cleared")
+	}
+}
+
+func TestGhostCombo(t *testing.T) {
+	state := NewState(10)
+	state.ExtraLifeScore = 0
+	for _, want := range []int{200, 400, 800, 1600, 1600} {
+		before := state.Score
+		state.EatGhost([2]int{1, 2})
+		if points := state.Score - before; points != want {
+			t.Errorf("ghost was worth %d, want %d", points, want)
+		}
+	}
+	state.ResetGhostCombo()
+	state.EatGhost([2]int{1, 2})
+	events := state.PollEvents()
+	if last := events[len(events)-1]; last != (Event{GhostEaten, [2]int{1, 2}, GhostScore}) {
+		t.Errorf("ghost after the combo was reset is %v, want %d points", last, GhostScore)
+	}
+}
+
+func TestExtraLife(t *testing.T) {
+	state := NewState(10)
+	state.ExtraLifeScore = 100
+	state.AddScore(90)
+	if state.Lives != StartLives {
+		t.Fatalf("lives are %d below the threshold, want %d", state.Lives, StartLives)
+	}
+	state.AddScore(10)
+	if state.Lives != StartLives+1 {
+		t.Fatalf("lives are %d at the threshold, want %d", state.Lives, StartLives+1)
+	}
+	state.AddScore(1000)
+	if state.Lives != StartLives+1 {
+		t.Errorf("lives are %d past the threshold, want only one extra life", state.Lives)
+	}
+	if got, want := state.PollEvents(), []Event{{Type: ExtraLife}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events are %v, want %v", got, want)
+	}
+
+	disabled := NewState(10)
+	disabled.ExtraLifeScore = 0
+	disabled.AddScore(DefaultExtraLifeScore)
+	if disabled.Lives != StartLives {
+		t.Errorf("lives are %d with extra lives disabled, want %d", disabled.Lives, StartLives)
+	}
+}
+
+// stepDeath updates state for the length of the death state and returns if the player respawned
+func stepDeath(state *State) bool {
+	respawn := false
+	for i := 0; i < int(DeathTime*TickRate)+1; i++ {
+		respawn = state.Update(StepTime) || respawn
+	}
+	return respawn
+}
+
+func TestDeathAndGameOver(t *testing.T) {
+	state := NewState(10)
+	pos := [2]int{3, 4}
+	for life := StartLives; life > 1; life-- {
+		state.Kill(pos)
+		if state.GetPhase() != Dying || state.Lives != life-1 {
+			t.Fatalf("phase is %v with %d lives after dying, want dying with %d", state.GetPhase(), state.Lives, life-1)
+		}
+		// the ghosts can not kill the player again while dying
+		state.Kill(pos)
+		if state.Lives != life-1 {
+			t.Fatalf("lives are %d after being killed while dying, want %d", state.Lives, life-1)
+		}
+		if state.Update(DeathTime / 2) {
+			t.Fatal("the player respawned half way through dying")
+		}
+		if !stepDeath(&state) || state.GetPhase() != Playing {
+			t.Fatalf("phase is %v after dying, want playing", state.GetPhase())
+		}
+		if got, want := state.PollEvents(), []Event{{Type: PlayerDied, Pos: pos}, {Type: PlayerRespawned}}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("events are %v, want %v", got, want)
+		}
+	}
+
+	state.Kill(pos)
+	if stepDeath(&state) {
+		t.Error("the player respawned after losing the last life")
+	}
+	if state.GetPhase() != Over || state.Lives != 0 {
+		t.Errorf("phase is %v with %d lives, want over with 0", state.GetPhase(), state.Lives)
+	}
+	if got, want := state.PollEvents(), []Event{{Type: PlayerDied, Pos: pos}, {Type: GameOver}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events are %v, want %v", got, want)
+	}
+	state.Kill(pos)
+	if state.Update(DeathTime) || state.Lives != 0 || len(state.PollEvents()) != 0 {
+		t.Error("the game changed after it was over")
+	}
+}
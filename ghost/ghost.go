@@ -0,0 +1,298 @@
+package ghost
+
+import (
+	"math/rand"
+	"strconv"
+
+	"github.com/sunkink29/3dpacman/movement"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// Personality selects which of the four arcade targeting rules a ghost uses while chasing
+type Personality int
+
+const (
+	Blinky Personality = iota // targets the player directly
+	Pinky                     // targets four tiles ahead of the player
+	Inky                      // doubles the vector from blinky to two tiles ahead of the player
+	Clyde                     // chases the player until within eight tiles then retreats to his corner
+)
+
+var personalityNames = [...]string{"blinky", "pinky", "inky", "clyde"}
+
+func (personality Personality) String() string {
+	if personality < 0 || int(personality) >= len(personalityNames) {
+		return "Personality(" + strconv.Itoa(int(personality)) + ")"
+	}
+	return personalityNames[personality]
+}
+
+// ParsePersonality returns the personality of the ghost with the given name
+func ParsePersonality(name string) (Personality, bool) {
+	for i, personalityName := range personalityNames {
+		if personalityName == name {
+			return Personality(i), true
+		}
+	}
+	return Blinky, false
+}
+
+// speed in tiles per second used until the level sets one
+const defaultSpeed = 4.5
+
+var personalityTex = []tile.TileType{tile.BlinkyTex, tile.PinkyTex, tile.InkyTex, tile.ClydeTex}
+
+// the order directions are checked in is the order the arcade breaks ties: up, left, down, right
+var directions = [4][2]int{{0, -1}, {-1, 0}, {0, 1}, {1, 0}}
+
+type Ghost struct {
+	personality Personality
+	home        [2]int
+	mover       movement.Mover
+	tile        tile.Tile
+	// set when the ghost is told to reverse while going through a tunnel, it turns around once through
+	reversePending bool
+	// frightened is cleared when the ghost is eaten so it goes back to normal for the rest of the phase
+	frightened bool
+
+	// the tile just outside the ghost house door that a ghost leaving the house heads for
+	exitPos       [2]int
+	startsInHouse bool
+	inHouse       bool
+	released      bool
+}
+
+// ChaseInfo holds the state of the board the targeting rules depend on
+type ChaseInfo struct {
+	PlayerPos [2]int
+	PlayerDir [2]int
+	BlinkyPos [2]int
+}
+
+func New(personality Personality, pos [2]int) Ghost {
+	tile := tile.NewTile(pos, 2, personalityTex[personality], 0)
+	return Ghost{
+		personality: personality,
+		home:        pos,
+		mover:       movement.New(pos, defaultSpeed),
+		tile:        tile,
+		exitPos:     pos,
+	}
+}
+
+func (curGhost *Ghost) SetPos(pos [2]int) {
+	curGhost.mover.SetPos(pos)
+	curGhost.reversePending = false
+}
+
+// SetHome moves the ghost to pos and makes it the tile the ghost returns to when reset.
+// If inHouse is true the ghost waits there until released and then leaves through exitPos.
+func (curGhost *Ghost) SetHome(pos [2]int, exitPos [2]int, inHouse bool) {
+	curGhost.home = pos
+	curGhost.exitPos = exitPos
+	curGhost.startsInHouse = inHouse
+	curGhost.ResetPos()
+}
+
+// ResetPos sends the ghost back to its home tile
+func (curGhost *Ghost) ResetPos() {
+	curGhost.SetPos(curGhost.home)
+	curGhost.frightened = false
+	curGhost.inHouse = curGhost.startsInHouse
+	curGhost.released = false
+}
+
+// Release lets a ghost waiting in the ghost house leave
+func (curGhost *Ghost) Release() {
+	curGhost.released = true
+}
+
+// IsWaiting returns true if the ghost is in the ghost house and has not been released
+func (curGhost *Ghost) IsWaiting() bool {
+	return curGhost.inHouse && !curGhost.released
+}
+
+func (curGhost *Ghost) IsInHouse() bool {
+	return curGhost.inHouse
+}
+
+func (curGhost *Ghost) GetPos() [2]int {
+	return curGhost.mover.GetPos()
+}
+
+// GetTargetPos returns the tile the ghost is moving to or {-1, -1} if it is not moving
+func (curGhost *Ghost) GetTargetPos() [2]int {
+	return curGhost.mover.GetTargetPos()
+}
+
+func (curGhost *Ghost) SetFrightened(frightened bool) {
+	curGhost.frightened = frightened
+}
+
+func (curGhost *Ghost) IsFrightened() bool {
+	return curGhost.frightened
+}
+
+// SetSpeed sets how many tiles per second the ghost moves
+func (curGhost *Ghost) SetSpeed(speed float64) {
+	curGhost.mover.SetSpeed(speed)
+}
+
+func (curGhost *Ghost) GetPersonality() Personality {
+	return curGhost.personality
+}
+
+// GetTile returns the tile to draw the ghost with alpha of the way between the last two simulation steps
+func (curGhost *Ghost) GetTile(alpha float64) tile.Tile {
+	curGhost.tile.Pos = curGhost.mover.GetDrawPos(alpha)
+	return curGhost.tile
+}
+
+// Reverse turns the ghost around, including when it is between two tiles.
+// A ghost in a tunnel finishes going through it before turning around.
+func (curGhost *Ghost) Reverse() {
+	if !curGhost.mover.Reverse() {
+		curGhost.reversePending = true
+	}
+}
+
+// Step picks the next tile for the ghost once it has reached its current target tile and then
+// advances the ghost by deltaTime seconds.
+// Frightened ghosts pick a random turn at every intersection using rng.
+func (curGhost *Ghost) Step(deltaTime float64, moves *movement.MoveMap, info ChaseInfo, mode Mode, rng *rand.Rand) {
+	if mode == Frightened {
+		curGhost.tile.Type = tile.FrightenedTex
+	} else {
+		curGhost.tile.Type = personalityTex[curGhost.personality]
+	}
+	if !curGhost.mover.IsMoving() && !curGhost.IsWaiting() {
+		curGhost.chooseMove(moves, info, mode, rng)
+	}
+	curGhost.mover.Step(deltaTime)
+}
+
+func (curGhost *Ghost) chooseMove(moves *movement.MoveMap, info ChaseInfo, mode Mode, rng *rand.Rand) {
+	pos := curGhost.mover.GetPos()
+	mapSize := moves.GetSize()
+	curDir := curGhost.mover.GetDir()
+	if curGhost.reversePending {
+		curDir = [2]int{-curDir[0], -curDir[1]}
+		curGhost.mover.SetDir(curDir)
+		curGhost.reversePending = false
+	}
+	if curGhost.inHouse && pos == curGhost.exitPos {
+		curGhost.inHouse = false
+	}
+	canEnter := CanEnterFunc(moves, pos, curGhost.inHouse)
+	var dir [2]int
+	switch {
+	case curGhost.inHouse:
+		dir = ChooseDirection(pos, curDir, curGhost.exitPos, canEnter)
+	case mode == Frightened:
+		dir = ChooseRandomDirection(pos, curDir, canEnter, rng)
+	case mode == Scatter:
+		dir = ChooseDirection(pos, curDir, ScatterTarget(curGhost.personality, mapSize), canEnter)
+	default:
+		target := ChaseTarget(curGhost.personality, pos, ScatterTarget(curGhost.personality, mapSize), info)
+		dir = ChooseDirection(pos, curDir, target, canEnter)
+	}
+	if canEnter([2]int{pos[0] + dir[0], pos[1] + dir[1]}) {
+		curGhost.mover.Start(moves.Target(pos, dir), dir)
+	}
+}
+
+// CanEnterFunc returns a function reporting if a ghost on pos may move onto the given tile next to it
+// using the same rules as the player: the tile must not be a wall and be on the map or one step
+// through a tunnel. The ghost house door can only be passed by ghosts leaving the house.
+func CanEnterFunc(moves *movement.MoveMap, pos [2]int, leavingHouse bool) func(nextPos [2]int) bool {
+	return func(nextPos [2]int) bool {
+		return moves.CanMove(pos, [2]int{nextPos[0] - pos[0], nextPos[1] - pos[1]}, leavingHouse)
+	}
+}
+
+// ScatterTarget returns the corner just outside the map that each ghost retreats to
+func ScatterTarget(personality Personality, mapSize [2]int) [2]int {
+	switch personality {
+	case Blinky:
+		return [2]int{mapSize[0] - 3, -4}
+	case Pinky:
+		return [2]int{2, -4}
+	case Inky:
+		return [2]int{mapSize[0] - 1, mapSize[1]}
+	default:
+		return [2]int{0, mapSize[1]}
+	}
+}
+
+// ChaseTarget returns the tile a ghost heads towards while chasing the player
+func ChaseTarget(personality Personality, ghostPos [2]int, scatterTarget [2]int, info ChaseInfo) [2]int {
+	switch personality {
+	case Pinky:
+		return aheadOfPlayer(info, 4)
+	case Inky:
+		pivot := aheadOfPlayer(info, 2)
+		return [2]int{2*pivot[0] - info.BlinkyPos[0], 2*pivot[1] - info.BlinkyPos[1]}
+	case Clyde:
+		if distSquared(ghostPos, info.PlayerPos) > 8*8 {
+			return info.PlayerPos
+		}
+		return scatterTarget
+	default:
+		return info.PlayerPos
+	}
+}
+
+// aheadOfPlayer returns the tile the given number of tiles in front of the player. Like the arcade
+// it is also that many tiles to the left when the player faces up, which came from an overflow bug.
+func aheadOfPlayer(info ChaseInfo, tiles int) [2]int {
+	ahead := [2]int{info.PlayerPos[0] + tiles*info.PlayerDir[0], info.PlayerPos[1] + tiles*info.PlayerDir[1]}
+	if info.PlayerDir == directions[0] {
+		ahead[0] -= tiles
+	}
+	return ahead
+}
+
+// ChooseDirection returns the direction a ghost at pos moving in dir takes to get closer to target.
+// A ghost never reverses unless it is at a dead end and ties are broken in the order up, left, down, right.
+func ChooseDirection(pos [2]int, dir [2]int, target [2]int, canEnter func(pos [2]int) bool) [2]int {
+	reverse := [2]int{-dir[0], -dir[1]}
+	best := reverse
+	bestDist := -1
+	for _, curDir := range directions {
+		if curDir == reverse {
+			continue
+		}
+		nextPos := [2]int{pos[0] + curDir[0], pos[1] + curDir[1]}
+		if !canEnter(nextPos) {
+			continue
+		}
+		if dist := distSquared(nextPos, target); bestDist == -1 || dist < bestDist {
+			best = curDir
+			bestDist = dist
+		}
+	}
+	return best
+}
+
+// ChooseRandomDirection returns a random direction a frightened ghost at pos moving in dir can take.
+// Like ChooseDirection the ghost only reverses at a dead end.
+func ChooseRandomDirection(pos [2]int, dir [2]int, canEnter func(pos [2]int) bool, rng *rand.Rand) [2]int {
+	reverse := [2]int{-dir[0], -dir[1]}
+	options := make([][2]int, 0, len(directions))
+	for _, curDir := range directions {
+		if curDir == reverse {
+			continue
+		}
+		if canEnter([2]int{pos[0] + curDir[0], pos[1] + curDir[1]}) {
+			options = append(options, curDir)
+		}
+	}
+	if len(options) == 0 {
+		return reverse
+	}
+	return options[rng.Intn(len(options))]
+}
+
+func distSquared(a [2]int, b [2]int) int {
+	return (a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1])
+}
@@ -0,0 +1,98 @@
+package ghost
+
+import "testing"
+
+var (
+	up    = [2]int{0, -1}
+	down  = [2]int{0, 1}
+	left  = [2]int{-1, 0}
+	right = [2]int{1, 0}
+)
+
+func TestScatterTarget(t *testing.T) {
+	mapSize := [2]int{28, 31}
+	tests := []struct {
+		personality Personality
+		want        [2]int
+	}{
+		{Blinky, [2]int{25, -4}},
+		{Pinky, [2]int{2, -4}},
+		{Inky, [2]int{27, 31}},
+		{Clyde, [2]int{0, 31}},
+	}
+	for _, test := range tests {
+		if got := ScatterTarget(test.personality, mapSize); got != test.want {
+			t.Errorf("%v scatter target is %v, want %v", test.personality, got, test.want)
+		}
+	}
+}
+
+func TestChaseTarget(t *testing.T) {
+	scatter := [2]int{0, 31}
+	tests := []struct {
+		name        string
+		personality Personality
+		ghostPos    [2]int
+		info        ChaseInfo
+		want        [2]int
+	}{
+		{"blinky targets the player", Blinky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, right, [2]int{1, 1}}, [2]int{10, 10}},
+		{"blinky ignores the player facing up", Blinky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, up, [2]int{1, 1}}, [2]int{10, 10}},
+
+		{"pinky ahead right", Pinky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, right, [2]int{}}, [2]int{14, 10}},
+		{"pinky ahead left", Pinky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, left, [2]int{}}, [2]int{6, 10}},
+		{"pinky ahead down", Pinky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, down, [2]int{}}, [2]int{10, 14}},
+		{"pinky ahead up and left", Pinky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, up, [2]int{}}, [2]int{6, 6}},
+		{"pinky player not moving", Pinky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, [2]int{}, [2]int{}}, [2]int{10, 10}},
+
+		{"inky doubles blinky to pivot right", Inky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, right, [2]int{8, 8}}, [2]int{16, 12}},
+		{"inky doubles blinky to pivot down", Inky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, down, [2]int{10, 16}}, [2]int{10, 8}},
+		{"inky pivot up and left", Inky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, up, [2]int{10, 12}}, [2]int{6, 4}},
+		{"inky on blinky", Inky, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, left, [2]int{8, 10}}, [2]int{8, 10}},
+
+		{"clyde far chases", Clyde, [2]int{1, 1}, ChaseInfo{[2]int{10, 10}, right, [2]int{}}, [2]int{10, 10}},
+		{"clyde eight tiles away retreats", Clyde, [2]int{2, 10}, ChaseInfo{[2]int{10, 10}, right, [2]int{}}, scatter},
+		{"clyde just over eight tiles chases", Clyde, [2]int{1, 10}, ChaseInfo{[2]int{10, 10}, right, [2]int{}}, [2]int{10, 10}},
+		{"clyde close retreats", Clyde, [2]int{9, 10}, ChaseInfo{[2]int{10, 10}, up, [2]int{}}, scatter},
+	}
+	for _, test := range tests {
+		if got := ChaseTarget(test.personality, test.ghostPos, scatter, test.info); got != test.want {
+			t.Errorf("%s: target is %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestChooseDirection(t *testing.T) {
+	open := func(pos [2]int) bool { return true }
+	only := func(allowed ...[2]int) func(pos [2]int) bool {
+		return func(pos [2]int) bool {
+			for _, allowedPos := range allowed {
+				if pos == allowedPos {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	pos := [2]int{5, 5}
+	tests := []struct {
+		name     string
+		dir      [2]int
+		target   [2]int
+		canEnter func(pos [2]int) bool
+		want     [2]int
+	}{
+		{"closest to target", right, [2]int{5, 20}, open, down},
+		{"never reverses", right, [2]int{0, 5}, open, up},
+		{"ties go up first", right, [2]int{5, 5}, open, up},
+		{"ties go left before down", down, [2]int{5, 5}, open, left},
+		{"ties go left before right", up, [2]int{5, 10}, only([2]int{4, 5}, [2]int{6, 5}), left},
+		{"walls are skipped", right, [2]int{5, 0}, only([2]int{5, 6}, [2]int{6, 5}), right},
+		{"reverses at a dead end", right, [2]int{20, 5}, only([2]int{4, 5}), left},
+	}
+	for _, test := range tests {
+		if got := ChooseDirection(pos, test.dir, test.target, test.canEnter); got != test.want {
+			t.Errorf("%s: direction is %v, want %v", test.name, got, test.want)
+		}
+	}
+}
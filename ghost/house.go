@@ -0,0 +1,51 @@
+package ghost
+
+// House decides when the ghosts waiting in the ghost house are released.
+// The ghost first in line (pinky, then inky, then clyde) counts the dots the player eats and leaves
+// once its count reaches its limit. If the player goes too long without eating a dot the ghost
+// first in line is released anyway.
+type House struct {
+	dotLimits    [4]int
+	dotCounters  [4]int
+	releaseTime  float64
+	timeSinceDot float64
+}
+
+func NewHouse(dotLimits [4]int, releaseTime float64) House {
+	return House{dotLimits: dotLimits, releaseTime: releaseTime}
+}
+
+// nextInLine returns the index of the ghost first in line to leave the house or -1 if none are waiting
+func nextInLine(ghosts []Ghost) int {
+	next := -1
+	for i := range ghosts {
+		if ghosts[i].IsWaiting() && (next == -1 || ghosts[i].personality < ghosts[next].personality) {
+			next = i
+		}
+	}
+	return next
+}
+
+// DotEaten counts a dot towards the ghost first in line and restarts the fallback timer
+func (house *House) DotEaten(ghosts []Ghost) {
+	house.timeSinceDot = 0
+	if next := nextInLine(ghosts); next != -1 {
+		house.dotCounters[ghosts[next].personality]++
+	}
+}
+
+// Update releases the ghost first in line once it has counted enough dots or the fallback timer runs out
+func (house *House) Update(deltaTime float64, ghosts []Ghost) {
+	house.timeSinceDot += deltaTime
+	next := nextInLine(ghosts)
+	if next == -1 {
+		return
+	}
+	personality := ghosts[next].personality
+	if house.dotCounters[personality] >= house.dotLimits[personality] {
+		ghosts[next].Release()
+	} else if house.releaseTime > 0 && house.timeSinceDot >= house.releaseTime {
+		house.timeSinceDot = 0
+		ghosts[next].Release()
+	}
+}
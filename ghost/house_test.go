@@ -0,0 +1,81 @@
+package ghost
+
+import "testing"
+
+// houseGhosts returns blinky outside the ghost house and the other ghosts waiting in it
+func houseGhosts() []Ghost {
+	exit := [2]int{5, 3}
+	ghosts := []Ghost{New(Blinky, exit), New(Pinky, [2]int{5, 5}), New(Inky, [2]int{4, 5}), New(Clyde, [2]int{6, 5})}
+	for i := range ghosts[1:] {
+		curGhost := &ghosts[i+1]
+		curGhost.SetHome(curGhost.GetPos(), exit, true)
+	}
+	return ghosts
+}
+
+// waiting returns which ghosts are waiting in the ghost house
+func waiting(ghosts []Ghost) [4]bool {
+	var isWaiting [4]bool
+	for i := range ghosts {
+		isWaiting[i] = ghosts[i].IsWaiting()
+	}
+	return isWaiting
+}
+
+func TestHouseReleaseByDots(t *testing.T) {
+	ghosts := houseGhosts()
+	house := NewHouse([4]int{0, 0, 30, 60}, 0)
+
+	// pinky needs no dots
+	house.Update(1.0/60, ghosts)
+	if got, want := waiting(ghosts), [4]bool{false, false, true, true}; got != want {
+		t.Fatalf("waiting is %v, want %v", got, want)
+	}
+
+	// only the ghost first in line counts dots, so clyde starts counting once inky has left
+	for dots := 1; dots <= 90; dots++ {
+		house.DotEaten(ghosts)
+		house.Update(1.0/60, ghosts)
+		want := [4]bool{false, false, dots < 30, dots < 90}
+		if got := waiting(ghosts); got != want {
+			t.Fatalf("after %d dots waiting is %v, want %v", dots, got, want)
+		}
+	}
+}
+
+func TestHouseReleaseByTimer(t *testing.T) {
+	ghosts := houseGhosts()
+	house := NewHouse([4]int{0, 10, 10, 10}, 4)
+
+	step := func(seconds float64) {
+		for i := 0; i < int(seconds*4); i++ {
+			house.Update(0.25, ghosts)
+		}
+	}
+	step(3.75)
+	if got, want := waiting(ghosts), [4]bool{false, true, true, true}; got != want {
+		t.Fatalf("waiting is %v, want %v", got, want)
+	}
+	step(0.25)
+	if got, want := waiting(ghosts), [4]bool{false, false, true, true}; got != want {
+		t.Fatalf("after 4 seconds waiting is %v, want %v", got, want)
+	}
+
+	// eating a dot restarts the timer
+	step(3)
+	house.DotEaten(ghosts)
+	step(3.75)
+	if got, want := waiting(ghosts), [4]bool{false, false, true, true}; got != want {
+		t.Fatalf("after eating a dot waiting is %v, want %v", got, want)
+	}
+	step(0.25)
+	if got, want := waiting(ghosts), [4]bool{false, false, false, true}; got != want {
+		t.Fatalf("waiting is %v, want %v", got, want)
+	}
+
+	// a ghost sent back to the house waits again
+	ghosts[1].ResetPos()
+	if !ghosts[1].IsWaiting() {
+		t.Error("pinky is not waiting after being reset")
+	}
+}
@@ -0,0 +1,89 @@
+package ghost
+
+// Mode is the global behaviour every ghost follows at a given time
+type Mode int
+
+const (
+	Scatter Mode = iota
+	Chase
+	Frightened
+)
+
+// ModeScheduler runs the global scatter/chase timer and the frightened timer that pauses it
+type ModeScheduler struct {
+	phases             []float64
+	phase              int
+	phaseTime          float64
+	frightenedDuration float64
+	frightenedTime     float64
+}
+
+func NewModeScheduler(phases []float64, frightenedDuration float64) ModeScheduler {
+	scheduler := ModeScheduler{phases: phases, frightenedDuration: frightenedDuration}
+	if len(phases) > 0 {
+		scheduler.phaseTime = phases[0]
+	}
+	return scheduler
+}
+
+// Update advances the timers and returns true when the ghosts have to reverse direction
+// which happens on every change between scatter and chase and when they become frightened
+func (scheduler *ModeScheduler) Update(deltaTime float64) bool {
+	if scheduler.frightenedTime > 0 {
+		scheduler.frightenedTime -= deltaTime
+		if scheduler.frightenedTime < 0 {
+			scheduler.frightenedTime = 0
+		}
+		return false
+	}
+	if scheduler.phase >= len(scheduler.phases) {
+		return false
+	}
+	scheduler.phaseTime -= deltaTime
+	if scheduler.phaseTime > 0 {
+		return false
+	}
+	scheduler.phase++
+	if scheduler.phase < len(scheduler.phases) {
+		scheduler.phaseTime += scheduler.phases[scheduler.phase]
+	} else {
+		scheduler.phaseTime = 0
+	}
+	return true
+}
+
+// Frighten starts (or restarts) the frightened phase and returns true when the ghosts have to reverse
+func (scheduler *ModeScheduler) Frighten() bool {
+	if scheduler.frightenedDuration <= 0 {
+		return false
+	}
+	wasFrightened := scheduler.frightenedTime > 0
+	scheduler.frightenedTime = scheduler.frightenedDuration
+	return !wasFrightened
+}
+
+func (scheduler *ModeScheduler) GetMode() Mode {
+	if scheduler.frightenedTime > 0 {
+		return Frightened
+	}
+	return scheduler.GetPhaseMode()
+}
+
+// GetPhaseMode returns the scatter or chase phase the timer is in, ignoring any frightened phase
+func (scheduler *ModeScheduler) GetPhaseMode() Mode {
+	if scheduler.phase%2 == 0 && scheduler.phase < len(scheduler.phases) {
+		return Scatter
+	}
+	return Chase
+}
+
+// GetTimeLeft returns the seconds left in the current mode or -1 if it lasts for the rest of the level
+func (scheduler *ModeScheduler) GetTimeLeft() float64 {
+	if scheduler.frightenedTime > 0 {
+		return scheduler.frightenedTime
+	}
+	if scheduler.phase >= len(scheduler.phases) {
+		return -1
+	}
+	return scheduler.phaseTime
+}
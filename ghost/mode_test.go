@@ -0,0 +1,108 @@
+package ghost
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sunkink29/3dpacman/game"
+)
+
+func TestModeSchedule(t *testing.T) {
+	for _, level := range []int{1, 2, 5} {
+		phases := game.GetLevel(level).Phases
+		scheduler := NewModeScheduler(phases, 0)
+		if mode := scheduler.GetMode(); mode != Scatter {
+			t.Fatalf("level %d starts in mode %v, want scatter", level, mode)
+		}
+
+		// every phase ends within a step of the sum of the phases before it and ends with a reversal
+		phase := 0
+		end := phases[0]
+		for step := 1; float64(step)*game.StepTime < end+60; step++ {
+			reverse := scheduler.Update(game.StepTime)
+			if !reverse {
+				continue
+			}
+			now := float64(step) * game.StepTime
+			if phase >= len(phases) {
+				t.Fatalf("level %d reversed at %.3f after the last phase", level, now)
+			}
+			if math.Abs(now-end) > game.StepTime+1e-9 {
+				t.Fatalf("level %d phase %d ended at %.3f, want %.3f", level, phase, now, end)
+			}
+			phase++
+			if phase < len(phases) {
+				end += phases[phase]
+			}
+			want := Scatter
+			if phase%2 == 1 || phase == len(phases) {
+				want = Chase
+			}
+			if mode := scheduler.GetMode(); mode != want {
+				t.Fatalf("level %d phase %d is %v, want %v", level, phase, mode, want)
+			}
+		}
+		if phase != len(phases) {
+			t.Fatalf("level %d reached phase %d, want %d", level, phase, len(phases))
+		}
+		// the chase after the last phase lasts for the rest of the level
+		for i := 0; i < 1000; i++ {
+			if scheduler.Update(game.StepTime) {
+				t.Fatalf("level %d reversed after the last phase", level)
+			}
+		}
+		if mode, timeLeft := scheduler.GetMode(), scheduler.GetTimeLeft(); mode != Chase || timeLeft != -1 {
+			t.Errorf("level %d ends in %v with %v seconds left, want chase and -1", level, mode, timeLeft)
+		}
+	}
+}
+
+func TestModeFrightened(t *testing.T) {
+	scheduler := NewModeScheduler([]float64{7, 20}, 6)
+	for i := 0; i < 12; i++ {
+		scheduler.Update(0.25)
+	}
+	if !scheduler.Frighten() {
+		t.Error("becoming frightened did not reverse the ghosts")
+	}
+	if mode, timeLeft := scheduler.GetMode(), scheduler.GetTimeLeft(); mode != Frightened || timeLeft != 6 {
+		t.Fatalf("mode is %v with %v seconds left, want frightened and 6", mode, timeLeft)
+	}
+	if phaseMode := scheduler.GetPhaseMode(); phaseMode != Scatter {
+		t.Errorf("phase mode while frightened is %v, want scatter", phaseMode)
+	}
+
+	// another big dot restarts the frightened time without a second reversal
+	for i := 0; i < 8; i++ {
+		scheduler.Update(0.25)
+	}
+	if scheduler.Frighten() {
+		t.Error("a big dot while frightened reversed the ghosts")
+	}
+
+	// the scatter timer is paused while frightened and the ghosts do not reverse once it ends
+	for i := 0; i < 24; i++ {
+		if scheduler.Update(0.25) {
+			t.Fatal("the ghosts reversed while frightened or when it ended")
+		}
+	}
+	if mode, timeLeft := scheduler.GetMode(), scheduler.GetTimeLeft(); mode != Scatter || timeLeft != 4 {
+		t.Fatalf("mode is %v with %v seconds left, want scatter and 4", mode, timeLeft)
+	}
+	for i := 0; i < 15; i++ {
+		if scheduler.Update(0.25) {
+			t.Fatal("scatter ended early")
+		}
+	}
+	if !scheduler.Update(0.25) {
+		t.Error("the ghosts did not reverse when scatter ended")
+	}
+	if mode := scheduler.GetMode(); mode != Chase {
+		t.Errorf("mode is %v, want chase", mode)
+	}
+
+	noFright := NewModeScheduler([]float64{7}, 0)
+	if noFright.Frighten() || noFright.GetMode() == Frightened {
+		t.Error("a level without frightened time frightened the ghosts")
+	}
+}
@@ -11,7 +11,7 @@ func init() {
 	registeredMouseButtonBinding = make([]mouseButtonBinding, 0)
 }
 
-type MouseButtonCallback func(w *glfw.Window, button glfw.MouseButton, mod glfw.ModifierKey)
+type MouseButtonCallback func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey)
 type mouseButtonBinding struct {
 	name     string
 	callback MouseButtonCallback
@@ -25,7 +25,26 @@ func RegisterMouseButtonBinding(name string, callback MouseButtonCallback) {
 
 func OnMouseButtonPress(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
 	for _, binding := range registeredMouseButtonBinding {
-		binding.callback(w, button, mod)
+		binding.callback(w, button, action, mod)
+	}
+}
+
+// CursorPosCallback is called with the position of the cursor in screen coordinates each time it moves
+type CursorPosCallback func(w *glfw.Window, xpos float64, ypos float64)
+type cursorPosBinding struct {
+	name     string
+	callback CursorPosCallback
+}
+
+var registeredCursorPosBinding []cursorPosBinding
+
+func RegisterCursorPosBinding(name string, callback CursorPosCallback) {
+	registeredCursorPosBinding = append(registeredCursorPosBinding, cursorPosBinding{name, callback})
+}
+
+func OnCursorMove(w *glfw.Window, xpos float64, ypos float64) {
+	for _, binding := range registeredCursorPosBinding {
+		binding.callback(w, xpos, ypos)
 	}
 }
 
@@ -46,7 +65,27 @@ func RegisterKeyBinding(key glfw.Key, name string, callback KeyCallback) {
 	}
 }
 
+// KeyCaptureCallback is called with every key while the keys are captured
+type KeyCaptureCallback func(w *glfw.Window, key glfw.Key, action glfw.Action, mods glfw.ModifierKey)
+
+var keyCapture KeyCaptureCallback
+
+// CaptureKeys sends every key to callback instead of the key bindings until ReleaseKeys is called,
+// so a dialog can be typed into without keys doing anything else
+func CaptureKeys(callback KeyCaptureCallback) {
+	keyCapture = callback
+}
+
+// ReleaseKeys sends keys to their key bindings again
+func ReleaseKeys() {
+	keyCapture = nil
+}
+
 func OnKeyPress(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	if keyCapture != nil {
+		keyCapture(w, key, action, mods)
+		return
+	}
 	binding, ok := registeredKeyBinding[key]
 	if ok {
 		binding.callback(w, action, mods)
@@ -0,0 +1,277 @@
+// Package levelselect draws a screen listing the maps in a directory with their metadata and
+// thumbnail so one can be picked to play.
+package levelselect
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	v41 "github.com/4ydx/gltext/v4.1"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/sunkink29/3dpacman/input"
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/rendering/text"
+)
+
+// the number of maps listed at once
+const visibleMaps = 10
+
+// the most characters shown on a line, longer lines are cut short
+const maxLineLength = 24
+
+// the number of lines used to show the metadata of the selected map
+const detailLines = 8
+
+const (
+	fontName   = "8bitmadness"
+	fontSize   = 30
+	lineHeight = 40
+)
+
+var (
+	textColor     = mgl32.Vec3{1, 1, 1}
+	selectedColor = mgl32.Vec3{1, 1, 0}
+	detailColor   = mgl32.Vec3{0.8, 0.8, 1}
+)
+
+type entry struct {
+	filename string
+	title    string
+	size     [2]int
+	meta     maps.Metadata
+	// the image saved with the map, nil if it has none
+	thumbnail image.Image
+}
+
+// Screen is the level select screen. It is hidden until it is opened with its key binding.
+type Screen struct {
+	dir      string
+	entries  []entry
+	selected int
+	open     bool
+	heading  *v41.Text
+	list     []*v41.Text
+	details  []*v41.Text
+	thumb    *thumbnail
+}
+
+// New creates a level select screen for the maps in dir, text.Init has to be called first
+func New(dir string) *Screen {
+	font := text.GetFont(fontName, fontSize)
+	screen := &Screen{
+		dir:     dir,
+		heading: text.New("Select Map", font, mgl32.Vec2{0, 260}, selectedColor),
+		list:    make([]*v41.Text, visibleMaps),
+		details: make([]*v41.Text, detailLines),
+		thumb:   newThumbnail(),
+	}
+	for i := range screen.list {
+		screen.list[i] = text.New("", font, mgl32.Vec2{-200, float32(200 - i*lineHeight)}, textColor)
+	}
+	for i := range screen.details {
+		screen.details[i] = text.New("", font, mgl32.Vec2{200, float32(200 - i*lineHeight)}, detailColor)
+	}
+	return screen
+}
+
+// RegisterBindings registers M to open and close the screen, Tab and Shift+Tab to change the
+// selected map and Enter to play it on curMap, calling onMapChanged once it has been replaced
+func (screen *Screen) RegisterBindings(curMap *maps.Map, onMapChanged func()) {
+	input.RegisterKeyBinding(glfw.KeyM, "Toggle Level Select", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			if !screen.open {
+				screen.Refresh()
+			}
+			screen.open = !screen.open
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyTab, "Select Next Map", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action != glfw.Release && screen.open && len(screen.entries) > 0 {
+			if mods&glfw.ModShift != 0 {
+				screen.selected = (screen.selected + len(screen.entries) - 1) % len(screen.entries)
+			} else {
+				screen.selected = (screen.selected + 1) % len(screen.entries)
+			}
+			screen.update()
+		}
+	})
+	input.RegisterKeyBinding(glfw.KeyEnter, "Play Selected Map", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release && screen.open && len(screen.entries) > 0 {
+			newMap, err := maps.Load(screen.entries[screen.selected].filename)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			*curMap = *newMap
+			screen.open = false
+			onMapChanged()
+		}
+	})
+}
+
+// IsOpen returns true while the screen is shown
+func (screen *Screen) IsOpen() bool {
+	return screen.open
+}
+
+// Refresh reads the metadata of the maps in the directory again. Maps that can not be loaded are left out.
+func (screen *Screen) Refresh() {
+	var filenames []string
+	for _, ext := range []string{".tmap", maps.TextExtension, maps.JSONExtension} {
+		matches, _ := filepath.Glob(filepath.Join(screen.dir, "*"+ext))
+		filenames = append(filenames, matches...)
+	}
+	sort.Strings(filenames)
+
+	selectedFile := ""
+	if screen.selected < len(screen.entries) {
+		selectedFile = screen.entries[screen.selected].filename
+	}
+	screen.entries = screen.entries[:0]
+	screen.selected = 0
+	for _, filename := range filenames {
+		curMap, err := maps.Load(filename)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if filename == selectedFile {
+			screen.selected = len(screen.entries)
+		}
+		thumbnail, err := curMap.GetThumbnail()
+		if err != nil {
+			fmt.Println(filename, err)
+		}
+		screen.entries = append(screen.entries, entry{filename, curMap.GetTitle(), curMap.GetSize(), curMap.GetMetadata(), thumbnail})
+	}
+	screen.update()
+}
+
+// update sets the text of every line and the thumbnail to show the selected map
+func (screen *Screen) update() {
+	// scroll the list so the selected map is always shown
+	first := 0
+	if screen.selected >= visibleMaps {
+		first = screen.selected - visibleMaps + 1
+	}
+	for i, line := range screen.list {
+		index := first + i
+		if index >= len(screen.entries) {
+			setLine(line, "")
+			continue
+		}
+		setLine(line, screen.entries[index].title)
+		if index == screen.selected {
+			line.SetColor(selectedColor)
+		} else {
+			line.SetColor(textColor)
+		}
+	}
+
+	var details []string
+	if len(screen.entries) == 0 {
+		details = []string{"No maps in", screen.dir}
+		screen.thumb.set(nil)
+	} else {
+		selected := screen.entries[screen.selected]
+		screen.thumb.set(selected.thumbnail)
+		meta := selected.meta
+		details = append(details, filepath.Base(selected.filename))
+		details = append(details, fmt.Sprint(selected.size[0], " x ", selected.size[1]))
+		if meta.Author != "" {
+			details = append(details, "By "+meta.Author)
+		}
+		if meta.Description != "" {
+			details = append(details, wrapText(meta.Description)...)
+		}
+		if len(meta.Levels) > 0 {
+			details = append(details, "Levels "+strings.Trim(fmt.Sprint(meta.Levels), "[]"))
+		}
+		if meta.ParScore != 0 {
+			details = append(details, "Par "+strconv.Itoa(meta.ParScore))
+		}
+		if !meta.Modified.IsZero() {
+			details = append(details, "Saved "+meta.Modified.Local().Format("2006-01-02"))
+		}
+	}
+	for i, line := range screen.details {
+		if i < len(details) {
+			setLine(line, details[i])
+		} else {
+			setLine(line, "")
+		}
+	}
+}
+
+// Draw draws the screen if it is open
+func (screen *Screen) Draw() {
+	if !screen.open {
+		return
+	}
+	screen.heading.Draw()
+	for _, line := range screen.list {
+		line.Draw()
+	}
+	for _, line := range screen.details {
+		line.Draw()
+	}
+	screen.thumb.draw()
+}
+
+// Release frees the text and thumbnail used by the screen
+func (screen *Screen) Release() {
+	screen.heading.Release()
+	for _, line := range screen.list {
+		line.Release()
+	}
+	for _, line := range screen.details {
+		line.Release()
+	}
+	screen.thumb.release()
+}
+
+// setLine shows str on line or hides the line if str is empty. The font only has the printable
+// ASCII characters so any others are replaced.
+func setLine(line *v41.Text, str string) {
+	if str == "" {
+		line.Hide()
+		return
+	}
+	str = strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '?'
+		}
+		return r
+	}, str)
+	if len(str) > maxLineLength {
+		str = str[:maxLineLength-3] + "..."
+	}
+	line.SetString("%s", str)
+	line.Show()
+}
+
+// wrapText splits str into lines that fit on the screen
+func wrapText(str string) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(str) {
+		if line != "" && len(line)+1+len(word) > maxLineLength {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
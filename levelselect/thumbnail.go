@@ -0,0 +1,118 @@
+package levelselect
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/sunkink29/3dpacman/rendering"
+)
+
+// the thumbnail of the selected map is scaled to fit in a box this big below its metadata, in the
+// coordinates text is positioned with
+var (
+	thumbnailCentre  = mgl32.Vec2{200, -190}
+	thumbnailMaxSize = mgl32.Vec2{200, 150}
+)
+
+// the corners of a square from 0, 0 to 1, 1 as two triangles
+var quadVertices = []float32{
+	0, 0, 1, 0, 1, 1,
+	0, 0, 1, 1, 0, 1,
+}
+
+// thumbnail draws the image of the selected map flat over the screen
+type thumbnail struct {
+	program uint32
+	vao     uint32
+	vbo     uint32
+	texture uint32
+	// the size the image is drawn at, zero when there is no image
+	size mgl32.Vec2
+}
+
+// newThumbnail compiles the shader used to draw thumbnails, the window has to be created first
+func newThumbnail() *thumbnail {
+	program, err := rendering.NewProgram(rendering.ImageVertexShader, rendering.ImageFragShader)
+	if err != nil {
+		panic(err)
+	}
+	gl.UseProgram(program)
+	gl.BindFragDataLocation(program, 0, gl.Str("outputColor\x00"))
+
+	var vao, vbo uint32
+	gl.GenVertexArrays(1, &vao)
+	gl.BindVertexArray(vao)
+	gl.GenBuffers(1, &vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(quadVertices)*4, gl.Ptr(quadVertices), gl.STATIC_DRAW)
+
+	vertAttrib := uint32(gl.GetAttribLocation(program, gl.Str("vert\x00")))
+	gl.EnableVertexAttribArray(vertAttrib)
+	gl.VertexAttribPointer(vertAttrib, 2, gl.FLOAT, false, 2*4, gl.PtrOffset(0))
+	return &thumbnail{program: program, vao: vao, vbo: vbo}
+}
+
+// set replaces the image that is drawn, nothing is drawn if img is nil
+func (thumb *thumbnail) set(img image.Image) {
+	thumb.clear()
+	if img == nil {
+		return
+	}
+	texture, err := rendering.NewImageTexture(img)
+	if err != nil {
+		fmt.Println("Error loading thumbnail:", err)
+		return
+	}
+	imgSize := img.Bounds().Size()
+	scale := thumbnailMaxSize[0] / float32(imgSize.X)
+	if heightScale := thumbnailMaxSize[1] / float32(imgSize.Y); heightScale < scale {
+		scale = heightScale
+	}
+	thumb.texture = texture
+	thumb.size = mgl32.Vec2{float32(imgSize.X), float32(imgSize.Y)}.Mul(scale)
+}
+
+// clear frees the image so nothing is drawn
+func (thumb *thumbnail) clear() {
+	if thumb.texture != 0 {
+		gl.DeleteTextures(1, &thumb.texture)
+		thumb.texture = 0
+	}
+	thumb.size = mgl32.Vec2{}
+}
+
+// draw draws the image over everything else on the screen
+func (thumb *thumbnail) draw() {
+	if thumb.texture == 0 {
+		return
+	}
+	gl.UseProgram(thumb.program)
+
+	corner := thumbnailCentre.Sub(thumb.size.Mul(0.5))
+	halfWindow := mgl32.Vec2{rendering.WindowWidth / 2, rendering.WindowHeight / 2}
+	rect := mgl32.Vec4{corner[0] / halfWindow[0], corner[1] / halfWindow[1], thumb.size[0] / halfWindow[0], thumb.size[1] / halfWindow[1]}
+	rectUniform := gl.GetUniformLocation(thumb.program, gl.Str("rect\x00"))
+	gl.Uniform4fv(rectUniform, 1, &rect[0])
+
+	// the tiles keep their textures on the other units, text binds its own to the first one
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, thumb.texture)
+	texUniform := gl.GetUniformLocation(thumb.program, gl.Str("tex\x00"))
+	gl.Uniform1i(texUniform, 0)
+
+	gl.Disable(gl.DEPTH_TEST)
+	gl.BindVertexArray(thumb.vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 2*3)
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+// release frees the image and the shader
+func (thumb *thumbnail) release() {
+	thumb.clear()
+	gl.DeleteBuffers(1, &thumb.vbo)
+	gl.DeleteVertexArrays(1, &thumb.vao)
+	gl.DeleteProgram(thumb.program)
+}
@@ -13,17 +13,27 @@ import (
 	"github.com/go-gl/glfw/v3.2/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 
+	"github.com/sunkink29/3dpacman/controls"
+	"github.com/sunkink29/3dpacman/editor"
+	"github.com/sunkink29/3dpacman/game"
 	"github.com/sunkink29/3dpacman/input"
+	"github.com/sunkink29/3dpacman/levelselect"
 	"github.com/sunkink29/3dpacman/maps"
-	"github.com/sunkink29/3dpacman/player"
 	"github.com/sunkink29/3dpacman/rendering"
 	"github.com/sunkink29/3dpacman/rendering/text"
+	"github.com/sunkink29/3dpacman/rendering/tiles"
 	"github.com/sunkink29/3dpacman/tile"
 )
 
-const speed = 5
+const cameraSpeed = 5
 const frameRate float64 = 60
 
+// seconds the points for a fruit are shown after it is eaten
+const fruitScoreShowTime = 2
+
+// the most time in seconds the simulation catches up on in one frame
+const maxFrameTime = 0.25
+
 var startMapSize = [2]int{28, 31}
 
 func init() {
@@ -61,6 +71,7 @@ func main() {
 
 	window.SetKeyCallback(input.OnKeyPress)
 	window.SetMouseButtonCallback(input.OnMouseButtonPress)
+	window.SetCursorPosCallback(input.OnCursorMove)
 
 	cameraPos := mgl32.Vec3{14, 0, 15.5}
 	// projectionMat := mgl32.Perspective(mgl32.DegToRad(45.0), float32(windowWidth)/windowHeight, 30.0, 50)
@@ -86,7 +97,22 @@ func main() {
 		}
 	})
 
-	tile.InitTileRendering(camera)
+	scoreText := text.New("Score 0", text.GetFont("8bitmadness", 30), mgl32.Vec2{-300, 280}, mgl32.Vec3{1, 1, 1})
+	defer scoreText.Release()
+	livesText := text.New("Lives "+strconv.Itoa(game.StartLives), text.GetFont("8bitmadness", 30), mgl32.Vec2{-300, -280}, mgl32.Vec3{1, 1, 1})
+	defer livesText.Release()
+	gameOverText := text.New("Game Over", text.GetFont("8bitmadness", 30), mgl32.Vec2{0, 0}, mgl32.Vec3{1, 0, 0})
+	defer gameOverText.Release()
+	gameOverText.Hide()
+	fruitScoreText := text.New("0", text.GetFont("8bitmadness", 30), mgl32.Vec2{0, 0}, mgl32.Vec3{1, 0.7, 0.8})
+	defer fruitScoreText.Release()
+	fruitScoreText.Hide()
+	fruitScoreTime := 0.0
+
+	levelSelect := levelselect.New("assets/maps")
+	defer levelSelect.Release()
+
+	tiles.Init(camera)
 
 	curMap := maps.CreateEmptyMap(startMapSize)
 
@@ -96,17 +122,36 @@ func main() {
 
 	// Configure global settings
 	gl.Enable(gl.DEPTH_TEST)
-	gl.DepthFunc(gl.LESS)
+	// LEQUAL so map layers drawn at the same height cover the ones drawn before them
+	gl.DepthFunc(gl.LEQUAL)
 	gl.ClearColor(0.5, 0.5, 0.5, 1.0)
 
 	// angle := 0.0
 	previousTime := time.Now()
+	// simulation time not yet run by Step
+	accumulator := 0.0
 	averageFrameRate := 0
 	frameCount := 0
 
+	// resetHud shows the score and lives of the game on curMap, it is called whenever the map is
+	// replaced or its game starts over
+	resetHud := func() {
+		state := curMap.GetState()
+		scoreText.SetString("Score " + strconv.Itoa(state.Score))
+		livesText.SetString("Lives " + strconv.Itoa(state.Lives))
+		if state.GetPhase() == game.Over {
+			gameOverText.Show()
+		} else {
+			gameOverText.Hide()
+		}
+		fruitScoreText.Hide()
+		fruitScoreTime = 0
+	}
+
 	rendering.RegisterMapBindings(&camera)
-	maps.RegisterMapBindings(&curMap, &testTile, &camera)
-	player.RegisterPlayerBindings()
+	editor.RegisterMapBindings(&curMap, &testTile, &camera, resetHud)
+	controls.RegisterPlayerBindings()
+	levelSelect.RegisterBindings(&curMap, resetHud)
 	input.RegisterKeyBinding(glfw.KeyEscape, "quit", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
 		w.SetShouldClose(true)
 	})
@@ -129,15 +174,57 @@ func main() {
 		//angle += deltaTime
 		// model := mgl32.HomogRotate3D(float32(angle), mgl32.Vec3{0, 1, 0})
 
-		rendering.UpdateCameraPosition(&camera, speed, deltaTime)
+		rendering.UpdateCameraPosition(&camera, cameraSpeed, deltaTime)
 		viewMat = mgl32.LookAtV(cameraPos.Add(mgl32.Vec3{0, 40, 0.1}), cameraPos, mgl32.Vec3{0, 1, 0})
-		curMap.Update()
+		accumulator += deltaTime
+		if accumulator > maxFrameTime {
+			// skip ahead instead of trying to catch up after a long pause
+			accumulator = maxFrameTime
+		}
+		if levelSelect.IsOpen() || editor.IsPromptOpen() {
+			// the game waits while a map is being picked or sized
+			accumulator = 0
+		}
+		for accumulator >= game.StepTime {
+			curMap.Step(controls.GetMovement())
+			accumulator -= game.StepTime
+		}
+		for _, event := range curMap.GetState().PollEvents() {
+			switch event.Type {
+			case game.PlayerDied, game.ExtraLife:
+				livesText.SetString("Lives " + strconv.Itoa(curMap.GetState().Lives))
+			case game.GameOver:
+				gameOverText.Show()
+			case game.FruitEaten:
+				worldPos := mgl32.Vec3{float32(event.Pos[0]), -1, float32(event.Pos[1])}
+				fruitScoreText.SetString(strconv.Itoa(event.Points))
+				fruitScoreText.SetPosition(rendering.WorldToTextSpace(worldPos, projectionMat.Mul4(viewMat)))
+				fruitScoreText.Show()
+				fruitScoreTime = fruitScoreShowTime
+			}
+			if event.Points > 0 {
+				scoreText.SetString("Score " + strconv.Itoa(curMap.GetState().Score))
+			}
+		}
+
+		if fruitScoreTime > 0 {
+			fruitScoreTime -= deltaTime
+			if fruitScoreTime <= 0 {
+				fruitScoreText.Hide()
+			}
+		}
 
 		// Render
-		tile.SetTileUniforms(viewMat)
-		testTile.Render()
-		curMap.Render(deltaTime)
+		tiles.SetUniforms(viewMat)
+		tiles.Render(testTile)
+		tiles.RenderMap(&curMap, accumulator/game.StepTime)
+		editor.Draw(&curMap)
 		frameRateText.Draw()
+		scoreText.Draw()
+		livesText.Draw()
+		gameOverText.Draw()
+		fruitScoreText.Draw()
+		levelSelect.Draw()
 
 		// Maintenance
 		window.SwapBuffers()
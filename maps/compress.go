@@ -0,0 +1,261 @@
+package maps
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+// Compression is how the tiles in a .tmap file are packed
+type Compression uint8
+
+const (
+	// NoCompression writes the tiles as they are
+	NoCompression Compression = iota
+	// RunLength writes each run of identical tiles as a uint16 count followed by the tile
+	RunLength
+	// Deflate compresses the tiles with DEFLATE
+	Deflate
+)
+
+var compressionNames = [...]string{"none", "rle", "deflate"}
+
+func (compression Compression) String() string {
+	if int(compression) >= len(compressionNames) {
+		return fmt.Sprint("Compression(", int(compression), ")")
+	}
+	return compressionNames[compression]
+}
+
+// ParseCompression returns the compression with the given name: none, rle or deflate
+func ParseCompression(name string) (Compression, bool) {
+	for i, compressionName := range compressionNames {
+		if compressionName == name {
+			return Compression(i), true
+		}
+	}
+	return NoCompression, false
+}
+
+// Checksum is the kind of checksum written at the end of a .tmap file so a corrupt file is not
+// loaded
+type Checksum uint8
+
+const (
+	// NoChecksum leaves the checksum out
+	NoChecksum Checksum = iota
+	// CRC32 catches accidental damage
+	CRC32
+	// SHA256 can also be used to check the file was not changed on purpose
+	SHA256
+)
+
+var checksumNames = [...]string{"none", "crc32", "sha256"}
+
+// the chunk each kind of checksum is written in
+var checksumChunks = [...]string{"", chunkCRC32, chunkSHA256}
+
+func (checksum Checksum) String() string {
+	if int(checksum) >= len(checksumNames) {
+		return fmt.Sprint("Checksum(", int(checksum), ")")
+	}
+	return checksumNames[checksum]
+}
+
+// ParseChecksum returns the checksum with the given name: none, crc32 or sha256
+func ParseChecksum(name string) (Checksum, bool) {
+	for i, checksumName := range checksumNames {
+		if checksumName == name {
+			return Checksum(i), true
+		}
+	}
+	return NoChecksum, false
+}
+
+// FileOptions are the optional features SaveToFile uses. A map loaded from a .tmap file keeps the
+// options it was saved with.
+type FileOptions struct {
+	Compression Compression
+	Checksum    Checksum
+}
+
+// GetFileOptions returns the options the map is saved to a .tmap file with
+func (curMap *Map) GetFileOptions() FileOptions {
+	return curMap.fileOptions
+}
+
+// SetFileOptions changes the options the map is saved to a .tmap file with
+func (curMap *Map) SetFileOptions(options FileOptions) {
+	curMap.fileOptions = options
+}
+
+// the most bytes a tile or layer chunk can have once unpacked
+const maxUnpackedSize = sizeofInt32*2 + MaxMapSize*MaxMapSize*sizeofTile
+
+// tileHeaderSize returns the number of bytes at the start of a chunk that come before its tiles
+func tileHeaderSize(id string) int {
+	switch id {
+	case chunkTiles:
+		return sizeofInt32 * 2
+	case chunkLayer:
+		return sizeofInt16
+	}
+	return 0
+}
+
+// pack returns the data of a packed chunk holding the chunk id with chunkData compressed.
+// It returns false if the chunk is not smaller when packed.
+func pack(compression Compression, id string, chunkData []byte) ([]byte, bool) {
+	data := append([]byte(id), byte(compression))
+	data = appendUint32(data, uint32(len(chunkData)))
+	switch compression {
+	case RunLength:
+		data = appendRunLength(data, chunkData, tileHeaderSize(id))
+	case Deflate:
+		var buf bytes.Buffer
+		writer, _ := flate.NewWriter(&buf, flate.BestCompression)
+		writer.Write(chunkData)
+		writer.Close()
+		data = append(data, buf.Bytes()...)
+	default:
+		return nil, false
+	}
+	return data, len(data)+sizeofChunkHeader < len(chunkData)
+}
+
+// appendRunLength copies the header and then writes each run of up to 65535 identical tiles as a
+// uint16 count and the tile
+func appendRunLength(data []byte, chunkData []byte, headerSize int) []byte {
+	data = append(data, chunkData[:headerSize]...)
+	tiles := chunkData[headerSize:]
+	for i := 0; i < len(tiles); {
+		run := 1
+		for run < 0xFFFF && i+(run+1)*sizeofTile <= len(tiles) &&
+			bytes.Equal(tiles[i:i+sizeofTile], tiles[i+run*sizeofTile:i+(run+1)*sizeofTile]) {
+			run++
+		}
+		data = appendUint16(data, uint16(run))
+		data = append(data, tiles[i:i+sizeofTile]...)
+		i += run * sizeofTile
+	}
+	return data
+}
+
+// unpack reads a packed chunk and returns the id and data of the chunk inside it.
+// offset is where data starts in the file and is only used to report errors.
+func unpack(data []byte, offset int) (string, []byte, Compression, error) {
+	headerSize := len(chunkTiles) + 1 + sizeofInt32
+	if len(data) < headerSize {
+		return "", nil, 0, &LoadError{ErrTruncated, offset + len(data), "missing packed chunk header"}
+	}
+	id := string(data[:len(chunkTiles)])
+	compression := Compression(data[len(chunkTiles)])
+	length := binary.LittleEndian.Uint32(data[len(chunkTiles)+1:])
+	if length > maxUnpackedSize {
+		return "", nil, 0, &LoadError{ErrOversized, offset, fmt.Sprintf("packed chunk %q is %d bytes", id, length)}
+	}
+	packed := data[headerSize:]
+	var unpacked []byte
+	var err error
+	switch compression {
+	case RunLength:
+		unpacked, err = readRunLength(packed, tileHeaderSize(id), int(length))
+	case Deflate:
+		reader := flate.NewReader(bytes.NewReader(packed))
+		unpacked, err = ioutil.ReadAll(io.LimitReader(reader, int64(length)+1))
+		reader.Close()
+	default:
+		return "", nil, 0, &LoadError{ErrBadCompression, offset + len(chunkTiles), fmt.Sprint("unknown compression ", int(compression))}
+	}
+	if err == nil && len(unpacked) != int(length) {
+		err = errors.New(fmt.Sprint("unpacked to ", len(unpacked), " bytes but should be ", length))
+	}
+	if err != nil {
+		return "", nil, 0, &LoadError{ErrBadCompression, offset + headerSize, fmt.Sprintf("chunk %q: %s", id, err)}
+	}
+	return id, unpacked, compression, nil
+}
+
+// readRunLength undoes appendRunLength, stopping with an error once more than length bytes would
+// be written
+func readRunLength(data []byte, headerSize int, length int) ([]byte, error) {
+	if len(data) < headerSize || length < headerSize {
+		return nil, ErrTruncated
+	}
+	unpacked := make([]byte, 0, length)
+	unpacked = append(unpacked, data[:headerSize]...)
+	data = data[headerSize:]
+	const sizeofRun = sizeofInt16 + sizeofTile
+	for len(data) > 0 {
+		if len(data) < sizeofRun {
+			return nil, ErrTruncated
+		}
+		run := int(binary.LittleEndian.Uint16(data))
+		if run == 0 || len(unpacked)+run*sizeofTile > length {
+			return nil, errors.New(fmt.Sprint("bad run of ", run, " tiles"))
+		}
+		for i := 0; i < run; i++ {
+			unpacked = append(unpacked, data[sizeofInt16:sizeofRun]...)
+		}
+		data = data[sizeofRun:]
+	}
+	return unpacked, nil
+}
+
+// checksumData returns the checksum of data the chunk for checksum holds
+func checksumData(checksum Checksum, data []byte) []byte {
+	switch checksum {
+	case CRC32:
+		return appendUint32(nil, crc32.ChecksumIEEE(data))
+	case SHA256:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}
+	return nil
+}
+
+// appendChecksum adds the checksum chunk for everything in data to its end
+func appendChecksum(data []byte, checksum Checksum) []byte {
+	if checksum == NoChecksum || int(checksum) >= len(checksumChunks) {
+		return data
+	}
+	return appendChunk(data, checksumChunks[checksum], checksumData(checksum, data))
+}
+
+// checkChecksum verifies the checksum chunk with the given id that starts at offset in file.
+// The chunk has to be the last one as it covers everything before it.
+func checkChecksum(file []byte, id string, chunkData []byte, offset int) (Checksum, error) {
+	checksum := SHA256
+	if id == chunkCRC32 {
+		checksum = CRC32
+	}
+	if offset+len(chunkData) != len(file) {
+		return checksum, &LoadError{ErrChecksum, offset, "checksum is not the last chunk"}
+	}
+	expected := checksumData(checksum, file[:offset-sizeofChunkHeader])
+	if !bytes.Equal(chunkData, expected) {
+		return checksum, &LoadError{ErrChecksum, offset, fmt.Sprintf("%s is %x but the file has %x", checksum, chunkData, expected)}
+	}
+	return checksum, nil
+}
+
+// checkTrailer verifies the checksum chunk at the end of file if there is one. It is checked before
+// the other chunks are read so a damaged file fails the checksum instead of whatever the damage broke.
+func checkTrailer(file []byte) (Checksum, error) {
+	for checksum := CRC32; checksum <= SHA256; checksum++ {
+		size := len(checksumData(checksum, nil))
+		start := len(file) - sizeofChunkHeader - size
+		if start < len(magic) || string(file[start:start+len(chunkTiles)]) != checksumChunks[checksum] ||
+			binary.LittleEndian.Uint32(file[start+len(chunkTiles):]) != uint32(size) {
+			continue
+		}
+		return checkChecksum(file, checksumChunks[checksum], file[start+sizeofChunkHeader:], start+sizeofChunkHeader)
+	}
+	return NoChecksum, nil
+}
@@ -0,0 +1,513 @@
+package maps
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sunkink29/3dpacman/ghost"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// A .tmap file starts with a four byte magic.
+//
+// Version 0 files use the magic "tmap" followed by the width and height as uint32 and then a
+// uint16 type and uint16 flags for every tile, column by column.
+//
+// Later versions use the magic "TMAP" followed by a uint16 format version and a list of chunks.
+// Each chunk is a four byte id, a uint32 length and that many bytes of data. Readers skip chunks
+// they do not know so new kinds of data can be added without breaking older versions of the game.
+// Version 2 added packed chunks, files without them are written as version 1 so older versions of
+// the game can still read them.
+// All numbers are little endian.
+const (
+	legacyMagic = "tmap"
+	magic       = "TMAP"
+	// FormatVersion is the newest version SaveToFile writes
+	FormatVersion = 2
+	// the version of files without packed chunks
+	unpackedVersion = 1
+)
+
+// chunk ids
+const (
+	// width and height as uint32 followed by the tiles laid out as in version 0. Each tile is the
+	// one the game sees when the layers are stacked, see Map.GetMapTile.
+	chunkTiles = "TILE"
+	// a uint16 layer followed by the tiles of that layer laid out as in the tile chunk. Each tile of
+	// the tile chunk is put on the layer its type belongs to and a layer chunk replaces the whole of
+	// its layer, so only layers that can not be worked out from the tile chunk are written.
+	chunkLayer = "LAYR"
+	// a uint16 ghost personality and the x and y of the tile it spawns on as uint32 for each ghost
+	// given a spawn by SetGhostSpawn
+	chunkGhostSpawns = "GSPN"
+	// the map metadata as a list of fields laid out like chunks, see the metadata field ids
+	chunkMetadata = "META"
+	// a compressed tile or layer chunk: the id of the chunk, a byte for its Compression, the
+	// uint32 length of its data once unpacked and then the packed data. It is read as if the
+	// unpacked chunk was in its place, offsets in errors inside it count from the packed data.
+	chunkPacked = "PACK"
+	// the CRC-32 as uint32 or SHA-256 of every byte of the file before the chunk. It has to be the
+	// last chunk in the file.
+	chunkCRC32  = "CR32"
+	chunkSHA256 = "S256"
+)
+
+// metadata field ids
+const (
+	// UTF-8 text
+	fieldTitle       = "TITL"
+	fieldAuthor      = "AUTH"
+	fieldDescription = "DESC"
+	// Unix time in seconds as int64
+	fieldCreated  = "CRTD"
+	fieldModified = "MODT"
+	// a uint16 for each recommended level
+	fieldLevels = "LVLS"
+	// uint32
+	fieldParScore = "PARS"
+	// PNG image
+	fieldThumbnail = "THMB"
+)
+
+// MaxMapSize is the largest width or height a map file may have
+const MaxMapSize = 1024
+
+// Errors returned when loading a malformed map, use errors.Is to check for them
+var (
+	ErrBadMagic           = errors.New("not a map file")
+	ErrTruncated          = errors.New("map file is truncated")
+	ErrSizeMismatch       = errors.New("map size and map data do not match")
+	ErrUnknownTileType    = errors.New("unknown tile type")
+	ErrOversized          = errors.New("map is too large")
+	ErrEmpty              = errors.New("map has no tiles")
+	ErrBadSpawn           = errors.New("bad ghost spawn")
+	ErrUnsupportedVersion = errors.New("map format version is newer than this version of the game supports")
+	ErrBadMetadata        = errors.New("bad map metadata")
+	ErrBadLayer           = errors.New("bad map layer")
+	ErrBadCompression     = errors.New("packed chunk can not be unpacked")
+	ErrChecksum           = errors.New("map file checksum does not match")
+)
+
+// LoadError describes what was wrong with a map file and where in the file it was found
+type LoadError struct {
+	Err    error
+	Offset int
+	Detail string
+}
+
+func (err *LoadError) Error() string {
+	msg := fmt.Sprint("Error loading map: ", err.Err, " at byte ", err.Offset)
+	if err.Detail != "" {
+		msg += ": " + err.Detail
+	}
+	return msg
+}
+
+func (err *LoadError) Unwrap() error {
+	return err.Err
+}
+
+const (
+	sizeofInt64 = 8
+	sizeofInt32 = 4
+	sizeofInt16 = 2
+	// bytes used by each tile in the tile data
+	sizeofTile = sizeofInt16 * 2
+	// bytes used by a chunk id and length
+	sizeofChunkHeader = len(chunkTiles) + sizeofInt32
+	// bytes used by each ghost in the ghost spawn chunk
+	sizeofGhostSpawn = sizeofInt16 + sizeofInt32*2
+)
+
+// encode returns the map in the current file format, packed and checksummed as set by SetFileOptions
+func (curMap *Map) encode() []byte {
+	var chunks []byte
+	version := uint16(unpackedVersion)
+	appendTiles := func(id string, chunkData []byte) {
+		if packed, ok := pack(curMap.fileOptions.Compression, id, chunkData); ok {
+			chunks = appendChunk(chunks, chunkPacked, packed)
+			version = FormatVersion
+		} else {
+			chunks = appendChunk(chunks, id, chunkData)
+		}
+	}
+	appendTiles(chunkTiles, curMap.encodeTiles())
+	for _, layer := range curMap.extraLayers(true) {
+		appendTiles(chunkLayer, curMap.encodeLayer(layer))
+	}
+	if len(curMap.ghostSpawns) > 0 {
+		chunks = appendChunk(chunks, chunkGhostSpawns, curMap.encodeGhostSpawns())
+	}
+	if !curMap.metadata.IsEmpty() {
+		chunks = appendChunk(chunks, chunkMetadata, curMap.metadata.encode())
+	}
+	data := []byte(magic)
+	data = appendUint16(data, version)
+	data = append(data, chunks...)
+	return appendChecksum(data, curMap.fileOptions.Checksum)
+}
+
+// encode returns the metadata fields that are set
+func (meta *Metadata) encode() []byte {
+	var data []byte
+	appendText := func(id string, text string) {
+		if text != "" {
+			data = appendChunk(data, id, []byte(text))
+		}
+	}
+	appendTime := func(id string, t time.Time) {
+		if !t.IsZero() {
+			data = appendChunk(data, id, appendUint64(nil, uint64(t.Unix())))
+		}
+	}
+	appendText(fieldTitle, meta.Title)
+	appendText(fieldAuthor, meta.Author)
+	appendText(fieldDescription, meta.Description)
+	appendTime(fieldCreated, meta.Created)
+	appendTime(fieldModified, meta.Modified)
+	if len(meta.Levels) > 0 {
+		levels := make([]byte, 0, len(meta.Levels)*sizeofInt16)
+		for _, level := range meta.Levels {
+			levels = appendUint16(levels, uint16(level))
+		}
+		data = appendChunk(data, fieldLevels, levels)
+	}
+	if meta.ParScore != 0 {
+		data = appendChunk(data, fieldParScore, appendUint32(nil, uint32(meta.ParScore)))
+	}
+	if len(meta.Thumbnail) > 0 {
+		data = appendChunk(data, fieldThumbnail, meta.Thumbnail)
+	}
+	return data
+}
+
+func (curMap *Map) encodeGhostSpawns() []byte {
+	data := make([]byte, 0, len(curMap.ghostSpawns)*sizeofGhostSpawn)
+	// write the ghosts in order so saving the same map always gives the same file
+	for personality := ghost.Blinky; personality <= ghost.Clyde; personality++ {
+		if pos, ok := curMap.ghostSpawns[personality]; ok {
+			data = appendUint16(data, uint16(personality))
+			data = appendUint32(data, uint32(pos[0]))
+			data = appendUint32(data, uint32(pos[1]))
+		}
+	}
+	return data
+}
+
+func (curMap *Map) encodeTiles() []byte {
+	data := make([]byte, 0, sizeofInt32*2+int(curMap.size[0]*curMap.size[1])*sizeofTile)
+	data = appendUint32(data, uint32(curMap.size[0]))
+	data = appendUint32(data, uint32(curMap.size[1]))
+	for i := 0; i < int(curMap.size[0]); i++ {
+		for j := 0; j < int(curMap.size[1]); j++ {
+			cTile := curMap.GetMapTile([2]int{i, j})
+			data = appendUint16(data, uint16(cTile.Type))
+			data = appendUint16(data, uint16(cTile.Flags))
+		}
+	}
+	return data
+}
+
+func (curMap *Map) encodeLayer(layer Layer) []byte {
+	data := make([]byte, 0, sizeofInt16+int(curMap.size[0]*curMap.size[1])*sizeofTile)
+	data = appendUint16(data, uint16(layer))
+	for _, col := range curMap.layers[layer] {
+		for _, cTile := range col {
+			data = appendUint16(data, uint16(cTile.Type))
+			data = appendUint16(data, uint16(cTile.Flags))
+		}
+	}
+	return data
+}
+
+// decodeMap reads a map in any supported version of the file format. Older versions are upgraded
+// to the current one as they are read so saving the map writes it in the current format.
+// Every field is checked so malformed data returns a *LoadError instead of panicking.
+func decodeMap(data []byte) (*Map, error) {
+	if len(data) < len(magic) {
+		return nil, &LoadError{ErrTruncated, len(data), "missing magic"}
+	}
+	switch string(data[:len(magic)]) {
+	case legacyMagic:
+		return decodeTiles(data[len(legacyMagic):], len(legacyMagic))
+	case magic:
+		return decodeChunks(data)
+	}
+	return nil, &LoadError{ErrBadMagic, 0, fmt.Sprintf("%q", data[:len(magic)])}
+}
+
+// decodeChunks reads the version and chunks that follow the magic of a versioned file
+func decodeChunks(file []byte) (*Map, error) {
+	data, offset := file[len(magic):], len(magic)
+	checksum, err := checkTrailer(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < sizeofInt16 {
+		return nil, &LoadError{ErrTruncated, offset + len(data), "missing format version"}
+	}
+	version := binary.LittleEndian.Uint16(data)
+	if version > FormatVersion {
+		return nil, &LoadError{ErrUnsupportedVersion, offset, fmt.Sprint("version ", version)}
+	}
+	data = data[sizeofInt16:]
+	offset += sizeofInt16
+
+	var newMap *Map
+	var ghostSpawns []byte
+	ghostSpawnsOffset := 0
+	var meta Metadata
+	var layers []chunk
+	options := FileOptions{Checksum: checksum}
+	var readChunk func(id string, chunkData []byte, chunkOffset int) error
+	readChunk = func(id string, chunkData []byte, chunkOffset int) error {
+		switch id {
+		case chunkTiles:
+			var err error
+			newMap, err = decodeTiles(chunkData, chunkOffset)
+			return err
+		case chunkGhostSpawns:
+			ghostSpawns = chunkData
+			ghostSpawnsOffset = chunkOffset
+		case chunkMetadata:
+			return meta.decode(chunkData, chunkOffset)
+		case chunkLayer:
+			layers = append(layers, chunk{chunkData, chunkOffset})
+		case chunkPacked:
+			packedID, unpacked, compression, err := unpack(chunkData, chunkOffset)
+			if err != nil {
+				return err
+			}
+			if packedID != chunkTiles && packedID != chunkLayer {
+				return &LoadError{ErrBadCompression, chunkOffset, fmt.Sprintf("chunk %q can not be packed", packedID)}
+			}
+			options.Compression = compression
+			return readChunk(packedID, unpacked, chunkOffset)
+		case chunkCRC32, chunkSHA256:
+			var err error
+			options.Checksum, err = checkChecksum(file, id, chunkData, chunkOffset)
+			return err
+		}
+		return nil
+	}
+	if err := readChunks(data, offset, readChunk); err != nil {
+		return nil, err
+	}
+	offset += len(data)
+	if newMap == nil {
+		return nil, &LoadError{ErrEmpty, offset, "no tile chunk"}
+	}
+	if err := newMap.decodeLayers(layers); err != nil {
+		return nil, err
+	}
+	if err := newMap.decodeGhostSpawns(ghostSpawns, ghostSpawnsOffset); err != nil {
+		return nil, err
+	}
+	newMap.metadata = meta
+	newMap.fileOptions = options
+	return newMap, nil
+}
+
+// chunk is the data of a chunk and where it starts in the file
+type chunk struct {
+	data   []byte
+	offset int
+}
+
+// decodeLayers replaces the layers worked out from the tile chunk with the ones in the layer chunks
+func (curMap *Map) decodeLayers(chunks []chunk) error {
+	size := curMap.GetSize()
+	for _, layerChunk := range chunks {
+		data, offset := layerChunk.data, layerChunk.offset
+		if expected := sizeofInt16 + size[0]*size[1]*sizeofTile; len(data) != expected {
+			return &LoadError{ErrSizeMismatch, offset, fmt.Sprint("layer chunk has ", len(data), " bytes but needs ", expected)}
+		}
+		layer := Layer(binary.LittleEndian.Uint16(data))
+		if !layer.IsValid() {
+			return &LoadError{ErrBadLayer, offset, fmt.Sprint("unknown layer ", int(layer))}
+		}
+		data = data[sizeofInt16:]
+		offset += sizeofInt16
+		for i, col := range curMap.layers[layer] {
+			for j := range col {
+				curIndex := (i*size[1] + j) * sizeofTile
+				tileType := tile.TileType(binary.LittleEndian.Uint16(data[curIndex:]))
+				if !tileType.IsValid() {
+					return &LoadError{ErrUnknownTileType, offset + curIndex, fmt.Sprint("type ", tileType, " at ", i, ",", j)}
+				}
+				if !layer.Allows(tileType) {
+					return &LoadError{ErrBadLayer, offset + curIndex, fmt.Sprint(tileType, " at ", i, ",", j, " can not be on the ", layer, " layer")}
+				}
+				col[j].Type = tileType
+				col[j].Flags = tile.TileFlag(binary.LittleEndian.Uint16(data[curIndex+sizeofInt16:]))
+			}
+		}
+	}
+	return nil
+}
+
+// readChunks calls read with the id, data and offset in the file of each chunk in data.
+// Chunks are read the same way whether they are in the file or inside another chunk.
+func readChunks(data []byte, offset int, read func(id string, data []byte, offset int) error) error {
+	for len(data) > 0 {
+		if len(data) < sizeofChunkHeader {
+			return &LoadError{ErrTruncated, offset + len(data), "missing chunk header"}
+		}
+		id := string(data[:len(chunkTiles)])
+		length := binary.LittleEndian.Uint32(data[len(chunkTiles):])
+		data = data[sizeofChunkHeader:]
+		offset += sizeofChunkHeader
+		if uint64(length) > uint64(len(data)) {
+			return &LoadError{ErrTruncated, offset + len(data), fmt.Sprintf("chunk %q", id)}
+		}
+		if err := read(id, data[:length], offset); err != nil {
+			return err
+		}
+		data = data[length:]
+		offset += int(length)
+	}
+	return nil
+}
+
+// decode reads the fields of the metadata chunk, fields it does not know are skipped
+func (meta *Metadata) decode(data []byte, offset int) error {
+	return readChunks(data, offset, func(id string, data []byte, offset int) error {
+		fixedSize := func(size int) error {
+			if len(data) != size {
+				return &LoadError{ErrSizeMismatch, offset, fmt.Sprintf("metadata field %q", id)}
+			}
+			return nil
+		}
+		switch id {
+		case fieldTitle:
+			meta.Title = string(data)
+		case fieldAuthor:
+			meta.Author = string(data)
+		case fieldDescription:
+			meta.Description = string(data)
+		case fieldCreated, fieldModified:
+			if err := fixedSize(sizeofInt64); err != nil {
+				return err
+			}
+			t := time.Unix(int64(binary.LittleEndian.Uint64(data)), 0).UTC()
+			if id == fieldCreated {
+				meta.Created = t
+			} else {
+				meta.Modified = t
+			}
+		case fieldLevels:
+			if len(data)%sizeofInt16 != 0 {
+				return &LoadError{ErrSizeMismatch, offset, fmt.Sprintf("metadata field %q", id)}
+			}
+			meta.Levels = make([]int, 0, len(data)/sizeofInt16)
+			for i := 0; i < len(data); i += sizeofInt16 {
+				level := int(binary.LittleEndian.Uint16(data[i:]))
+				if level == 0 {
+					return &LoadError{ErrBadMetadata, offset + i, "level 0"}
+				}
+				meta.Levels = append(meta.Levels, level)
+			}
+		case fieldParScore:
+			if err := fixedSize(sizeofInt32); err != nil {
+				return err
+			}
+			meta.ParScore = int(binary.LittleEndian.Uint32(data))
+		case fieldThumbnail:
+			if len(data) > MaxThumbnailSize {
+				return &LoadError{ErrOversized, offset, fmt.Sprint("thumbnail is ", len(data), " bytes")}
+			}
+			meta.Thumbnail = append([]byte(nil), data...)
+		}
+		return nil
+	})
+}
+
+// decodeGhostSpawns reads the ghost spawn chunk once the tiles have been read
+func (curMap *Map) decodeGhostSpawns(data []byte, offset int) error {
+	if len(data)%sizeofGhostSpawn != 0 {
+		return &LoadError{ErrSizeMismatch, offset, "ghost spawn chunk"}
+	}
+	for i := 0; i < len(data); i += sizeofGhostSpawn {
+		personality := ghost.Personality(binary.LittleEndian.Uint16(data[i:]))
+		pos := [2]uint32{
+			binary.LittleEndian.Uint32(data[i+sizeofInt16:]),
+			binary.LittleEndian.Uint32(data[i+sizeofInt16+sizeofInt32:]),
+		}
+		if personality > ghost.Clyde {
+			return &LoadError{ErrBadSpawn, offset + i, fmt.Sprint("unknown ghost ", personality)}
+		}
+		if pos[0] >= uint32(curMap.size[0]) || pos[1] >= uint32(curMap.size[1]) {
+			return &LoadError{ErrBadSpawn, offset + i, fmt.Sprint(personality, " spawns outside of the map")}
+		}
+		curMap.SetGhostSpawn(personality, [2]int{int(pos[0]), int(pos[1])})
+	}
+	return nil
+}
+
+// decodeTiles reads the map size and tiles, the whole of a version 0 file after the magic.
+// Each tile is put on the layer its type belongs to.
+// offset is where data starts in the file and is only used to report errors.
+func decodeTiles(data []byte, offset int) (*Map, error) {
+	if len(data) < sizeofInt32*2 {
+		return nil, &LoadError{ErrTruncated, offset + len(data), "missing map size"}
+	}
+	width := binary.LittleEndian.Uint32(data[0:sizeofInt32])
+	height := binary.LittleEndian.Uint32(data[sizeofInt32 : sizeofInt32*2])
+	if width > MaxMapSize || height > MaxMapSize {
+		return nil, &LoadError{ErrOversized, offset, fmt.Sprint(width, "x", height)}
+	}
+	if width == 0 || height == 0 {
+		return nil, &LoadError{ErrEmpty, offset, fmt.Sprint(width, "x", height)}
+	}
+	mapSize := [2]int{int(width), int(height)}
+	data = data[sizeofInt32*2:]
+	offset += sizeofInt32 * 2
+
+	if expected := mapSize[0] * mapSize[1] * sizeofTile; len(data) != expected {
+		err := ErrSizeMismatch
+		if len(data) < expected {
+			err = ErrTruncated
+		}
+		return nil, &LoadError{err, offset, fmt.Sprint(width, "x", height, " map needs ", expected, " bytes of tiles but has ", len(data))}
+	}
+	newMap := CreateEmptyMap(mapSize)
+	for i := 0; i < mapSize[0]; i++ {
+		for j := 0; j < mapSize[1]; j++ {
+			curIndex := (i*mapSize[1] + j) * sizeofTile
+			tileType := tile.TileType(binary.LittleEndian.Uint16(data[curIndex : curIndex+sizeofInt16]))
+			if !tileType.IsValid() {
+				return nil, &LoadError{ErrUnknownTileType, offset + curIndex, fmt.Sprint("type ", tileType, " at ", i, ",", j)}
+			}
+			cTile := &newMap.layers[LayerOf(tileType)][i][j]
+			cTile.Type = tileType
+			cTile.Flags = tile.TileFlag(binary.LittleEndian.Uint16(data[curIndex+sizeofInt16 : curIndex+sizeofTile]))
+		}
+	}
+	return &newMap, nil
+}
+
+func appendChunk(data []byte, id string, chunkData []byte) []byte {
+	data = append(data, id...)
+	data = appendUint32(data, uint32(len(chunkData)))
+	return append(data, chunkData...)
+}
+
+func appendUint64(data []byte, value uint64) []byte {
+	bs := make([]byte, sizeofInt64)
+	binary.LittleEndian.PutUint64(bs, value)
+	return append(data, bs...)
+}
+
+func appendUint32(data []byte, value uint32) []byte {
+	bs := make([]byte, sizeofInt32)
+	binary.LittleEndian.PutUint32(bs, value)
+	return append(data, bs...)
+}
+
+func appendUint16(data []byte, value uint16) []byte {
+	bs := make([]byte, sizeofInt16)
+	binary.LittleEndian.PutUint16(bs, value)
+	return append(data, bs...)
+}
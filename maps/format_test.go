@@ -0,0 +1,275 @@
+package maps
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// tmapFile returns a versioned file holding the given chunk
+func tmapFile(id string, chunkData []byte) []byte {
+	data := appendUint16([]byte(magic), unpackedVersion)
+	return appendChunk(data, id, chunkData)
+}
+
+// tileChunk returns a tile chunk of the given size holding tiles, which may not match the size
+func tileChunk(width, height uint32, tiles ...tile.TileType) []byte {
+	data := appendUint32(nil, width)
+	data = appendUint32(data, height)
+	for _, tileType := range tiles {
+		data = appendUint16(data, uint16(tileType))
+		data = appendUint16(data, 0)
+	}
+	return data
+}
+
+func TestLoadMapErrors(t *testing.T) {
+	checksummed := CreateEmptyMap([2]int{3, 3})
+	checksummed.SetFileOptions(FileOptions{Checksum: CRC32})
+	corrupt := checksummed.encode()
+	// a byte in the middle of the tiles
+	corrupt[len(magic)+sizeofInt16+sizeofChunkHeader+sizeofInt32*2+1] ^= 0xFF
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"bad magic", []byte("PAMT\x01\x00"), ErrBadMagic},
+		{"truncated header", []byte("TMAP\x01"), ErrTruncated},
+		{"truncated chunk", tmapFile(chunkTiles, tileChunk(2, 2, tile.Blank))[:len(magic)+sizeofInt16+sizeofChunkHeader+4], ErrTruncated},
+		{"size mismatch", tmapFile(chunkTiles, tileChunk(1, 1, tile.Blank, tile.Blank)), ErrSizeMismatch},
+		{"unknown tile type", tmapFile(chunkTiles, tileChunk(1, 1, tile.TileType(0xFFFF))), ErrUnknownTileType},
+		{"oversized dimensions", tmapFile(chunkTiles, tileChunk(MaxMapSize+1, 1)), ErrOversized},
+		{"bad checksum", corrupt, ErrChecksum},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			newMap, err := LoadMap(test.data)
+			if newMap != nil {
+				t.Errorf("LoadMap returned a map with error %v", err)
+			}
+			var loadErr *LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("LoadMap error = %v, want a *LoadError", err)
+			}
+			if !errors.Is(err, test.want) {
+				t.Errorf("LoadMap error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestParScoreRange(t *testing.T) {
+	curMap := CreateEmptyMap([2]int{2, 2})
+	meta := curMap.GetMetadata()
+	meta.ParScore = maxParScore
+	if strconv.IntSize < 64 {
+		meta.ParScore = math.MaxInt32
+	}
+	if err := curMap.SetMetadata(meta); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"par.tmap", "par" + TextExtension, "par" + JSONExtension}
+	for _, name := range names {
+		filename := filepath.Join(t.TempDir(), name)
+		if err := curMap.Save(filename); err != nil {
+			t.Fatal(err)
+		}
+		newMap, err := Load(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if par := newMap.GetMetadata().ParScore; par != meta.ParScore {
+			t.Errorf("%s: par score = %d, want %d", name, par, meta.ParScore)
+		}
+	}
+
+	for _, par := range []int64{-1, maxParScore + 1} {
+		if int64(int(par)) != par {
+			continue
+		}
+		meta.ParScore = int(par)
+		if err := curMap.SetMetadata(meta); err == nil {
+			t.Errorf("par score %d was set", par)
+		}
+		// metadata set inside the package is checked again when the map is saved
+		curMap.metadata.ParScore = int(par)
+		for _, name := range names {
+			if err := curMap.Save(filepath.Join(t.TempDir(), name)); err == nil {
+				t.Errorf("%s: map with par score %d was saved", name, par)
+			}
+		}
+	}
+}
+
+func TestLoadMapValid(t *testing.T) {
+	data := tmapFile(chunkTiles, tileChunk(2, 1, tile.Wall, tile.Dot))
+	newMap, err := LoadMap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := newMap.GetSize(); size != [2]int{2, 1} {
+		t.Errorf("size = %v, want [2 1]", size)
+	}
+	if tileType := newMap.GetMapTile([2]int{1, 0}).Type; tileType != tile.Dot {
+		t.Errorf("tile 1,0 = %v, want %v", tileType, tile.Dot)
+	}
+}
+
+// chunkIDs returns the ids of the chunks in a versioned map file
+func chunkIDs(t *testing.T, data []byte) []string {
+	var ids []string
+	err := readChunks(data[len(magic)+sizeofInt16:], len(magic)+sizeofInt16, func(id string, data []byte, offset int) error {
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestEncodeLayers(t *testing.T) {
+	curMap := CreateEmptyMap([2]int{3, 2})
+	curMap.ChangeMapTile([2]int{0, 0}, tile.Wall, 0)
+	curMap.ChangeMapTile([2]int{1, 0}, tile.Wall, 0)
+	curMap.ChangeMapTile([2]int{2, 0}, tile.GhostDoor, 0)
+	curMap.ChangeMapTile([2]int{0, 1}, tile.Tunnel, 0)
+	curMap.ChangeMapTile([2]int{1, 1}, tile.DotBig, 0)
+	curMap.ChangeMapTile([2]int{2, 1}, tile.PlayerSpawn, 0)
+	// checkLayers encodes the map and checks it has want layer chunks and loads with the same layers
+	checkLayers := func(want int) {
+		data := curMap.encode()
+		layers := 0
+		for _, id := range chunkIDs(t, data) {
+			if id == chunkLayer {
+				layers++
+			}
+		}
+		if layers != want {
+			t.Errorf("%d layer chunks were written, want %d", layers, want)
+		}
+		newMap, err := LoadMap(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for layer := Layer(0); layer < NumLayers; layer++ {
+			for x := 0; x < 3; x++ {
+				for y := 0; y < 2; y++ {
+					pos := [2]int{x, y}
+					if got, want := newMap.GetLayerTile(layer, pos), curMap.GetLayerTile(layer, pos); got.Type != want.Type || got.Flags != want.Flags {
+						t.Errorf("%v tile at %v is %v %v, want %v %v", layer, pos, got.Type, got.Flags, want.Type, want.Flags)
+					}
+				}
+			}
+		}
+	}
+	// every layer can be worked out from the tiles
+	checkLayers(0)
+	// a dot under a wall is hidden from the tile chunk so only the pickups layer has to be written
+	curMap.PlaceTile(Pickups, [2]int{0, 0}, tile.Dot, 0)
+	checkLayers(1)
+}
+
+func TestLoadLegacyMap(t *testing.T) {
+	// bigTestMap.tmap as it was shipped before the format had a version
+	data, err := ioutil.ReadFile("testdata/bigTestMap.tmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:len(legacyMagic)]) != legacyMagic {
+		t.Fatalf("testdata/bigTestMap.tmap starts with %q, want %q", data[:len(legacyMagic)], legacyMagic)
+	}
+	legacyMap, err := LoadMap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := legacyMap.GetSize(); size != [2]int{28, 31} {
+		t.Fatalf("size is %v, want [28 31]", size)
+	}
+	tests := []struct {
+		pos      [2]int
+		layer    Layer
+		tileType tile.TileType
+	}{
+		{[2]int{0, 0}, Walls, tile.Wall},
+		{[2]int{8, 0}, Walls, tile.Blank},
+		{[2]int{1, 1}, Pickups, tile.DotBig},
+		{[2]int{2, 1}, Pickups, tile.Dot},
+		{[2]int{8, 4}, Triggers, tile.PlayerSpawn},
+		{[2]int{27, 30}, Floor, tile.Blank},
+	}
+	for _, test := range tests {
+		if got := legacyMap.GetLayerTile(test.layer, test.pos).Type; got != test.tileType {
+			t.Errorf("%v tile at %v is %v, want %v", test.layer, test.pos, got, test.tileType)
+		}
+	}
+
+	saved := legacyMap.encode()
+	if string(saved[:len(magic)]) != magic {
+		t.Fatalf("saved map starts with %q, want %q", saved[:len(magic)], magic)
+	}
+	newMap, err := LoadMap(saved)
+	if err != nil {
+		t.Fatal("map does not load after saving:", err)
+	}
+	size := legacyMap.GetSize()
+	if newSize := newMap.GetSize(); newSize != size {
+		t.Fatalf("size is %v after saving, want %v", newSize, size)
+	}
+	for layer := Layer(0); layer < NumLayers; layer++ {
+		for x := 0; x < size[0]; x++ {
+			for y := 0; y < size[1]; y++ {
+				pos := [2]int{x, y}
+				if got, want := newMap.GetLayerTile(layer, pos), legacyMap.GetLayerTile(layer, pos); got.Type != want.Type || got.Flags != want.Flags {
+					t.Fatalf("%v tile at %v is %v %v after saving, want %v %v", layer, pos, got.Type, got.Flags, want.Type, want.Flags)
+				}
+			}
+		}
+	}
+}
+
+func FuzzLoad(f *testing.F) {
+	files, err := filepath.Glob("../assets/maps/*")
+	if err != nil {
+		f.Fatal(err)
+	}
+	legacyFiles, err := filepath.Glob("testdata/*.tmap")
+	if err != nil {
+		f.Fatal(err)
+	}
+	files = append(files, legacyFiles...)
+	for _, filename := range files {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		newMap, err := LoadMap(data)
+		if err != nil {
+			if newMap != nil {
+				t.Fatal("LoadMap returned a map and an error")
+			}
+			return
+		}
+		saved, err := LoadMap(newMap.encode())
+		if err != nil {
+			t.Fatal("map does not load after saving:", err)
+		}
+		if saved.GetSize() != newMap.GetSize() {
+			t.Fatal("map size changed after saving")
+		}
+		// a map that loads must also be playable
+		for i := 0; i < 60; i++ {
+			newMap.Step([2]int{1, 0})
+		}
+	})
+}
@@ -0,0 +1,301 @@
+package maps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/sunkink29/3dpacman/ghost"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// JSONExtension is the file extension of JSON maps
+const JSONExtension = ".json"
+
+// jsonVersion is the version of the JSON map layout described by map.schema.json
+const jsonVersion = 1
+
+// jsonMap is the layout of a JSON map, see map.schema.json
+type jsonMap struct {
+	Version int `json:"version"`
+	Width   int `json:"width"`
+	Height  int `json:"height"`
+	// Tiles holds one array per row of the map, indexed [y][x]. Each tile is the one the game sees
+	// when the layers are stacked and is put on the layer its type belongs to.
+	Tiles [][]jsonTile `json:"tiles"`
+	// Layers holds the layers that can not be worked out from Tiles by name, laid out like Tiles
+	Layers map[string][][]jsonTile `json:"layers,omitempty"`
+	Spawns *jsonSpawns             `json:"spawns,omitempty"`
+	// GhostSpawns holds the tiles set by SetGhostSpawn by ghost name
+	GhostSpawns map[string][2]int `json:"ghostSpawns,omitempty"`
+	Metadata    *jsonMetadata     `json:"metadata,omitempty"`
+}
+
+type jsonMetadata struct {
+	Title       string `json:"title,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Description string `json:"description,omitempty"`
+	// RFC 3339 timestamps
+	Created  string `json:"created,omitempty"`
+	Modified string `json:"modified,omitempty"`
+	Levels   []int  `json:"levels,omitempty"`
+	ParScore int    `json:"parScore,omitempty"`
+	// a base64 encoded PNG image
+	Thumbnail []byte `json:"thumbnail,omitempty"`
+	// Name is the file name older versions of the game wrote, it is used as the title if there is none
+	Name string `json:"name,omitempty"`
+}
+
+type jsonTile struct {
+	Type  string   `json:"type"`
+	Flags []string `json:"flags,omitempty"`
+}
+
+// jsonSpawns are worked out from the tiles and are only written for the benefit of other tools,
+// they are ignored when a map is read
+type jsonSpawns struct {
+	Player    [2]int  `json:"player"`
+	Ghosts    [2]int  `json:"ghosts"`
+	GhostDoor *[2]int `json:"ghostDoor,omitempty"`
+	Fruit     *[2]int `json:"fruit,omitempty"`
+}
+
+// SaveToJSON saves the map as a JSON map
+func (curMap *Map) SaveToJSON(filename string) error {
+	if !strings.HasSuffix(filename, JSONExtension) {
+		filename += JSONExtension
+	}
+
+	if err := curMap.metadata.check(); err != nil {
+		return err
+	}
+	curMap.touchMetadata()
+	data, err := json.MarshalIndent(curMap, "", "\t")
+	if err != nil {
+		return errors.New(fmt.Sprint("Error Saving Map: ", err))
+	}
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		return errors.New(fmt.Sprint("Error Saving Map to file:", err))
+	}
+	curMap.filename = filename
+	return nil
+}
+
+// LoadMapFromJSON reads a map saved by SaveToJSON
+func LoadMapFromJSON(filename string) (*Map, error) {
+	mapBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprint("Error Reading Map:", err))
+	}
+	var newMap Map
+	if err := json.Unmarshal(mapBytes, &newMap); err != nil {
+		return nil, err
+	}
+	newMap.filename = filename
+	return &newMap, nil
+}
+
+func (curMap *Map) MarshalJSON() ([]byte, error) {
+	jMap := jsonMap{
+		Version: jsonVersion,
+		Width:   int(curMap.size[0]),
+		Height:  int(curMap.size[1]),
+		Tiles:   make([][]jsonTile, curMap.size[1]),
+		Spawns: &jsonSpawns{
+			Player: curMap.GetPlayerSpawn(),
+			Ghosts: curMap.GetGhostSpawn(),
+		},
+	}
+	if door, _, ok := curMap.GetGhostDoor(); ok {
+		jMap.Spawns.GhostDoor = &door
+	}
+	if fruit, ok := curMap.GetFruitSpawn(); ok {
+		jMap.Spawns.Fruit = &fruit
+	}
+	if len(curMap.ghostSpawns) > 0 {
+		jMap.GhostSpawns = make(map[string][2]int, len(curMap.ghostSpawns))
+		for personality, pos := range curMap.ghostSpawns {
+			jMap.GhostSpawns[personality.String()] = pos
+		}
+	}
+	if !curMap.metadata.IsEmpty() {
+		meta := curMap.metadata.toJSON()
+		jMap.Metadata = &meta
+	}
+	var err error
+	if jMap.Tiles, err = curMap.encodeJSONTiles(curMap.GetMapTile); err != nil {
+		return nil, err
+	}
+	for _, layer := range curMap.extraLayers(true) {
+		if jMap.Layers == nil {
+			jMap.Layers = make(map[string][][]jsonTile)
+		}
+		getTile := func(pos [2]int) tile.Tile { return curMap.GetLayerTile(layer, pos) }
+		if jMap.Layers[layer.String()], err = curMap.encodeJSONTiles(getTile); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(jMap)
+}
+
+// encodeJSONTiles returns the tiles given by getTile for each position as rows
+func (curMap *Map) encodeJSONTiles(getTile func(pos [2]int) tile.Tile) ([][]jsonTile, error) {
+	rows := make([][]jsonTile, curMap.size[1])
+	for y := range rows {
+		row := make([]jsonTile, curMap.size[0])
+		for x := range row {
+			cTile := getTile([2]int{x, y})
+			if cTile.Flags&^tile.All != 0 {
+				return nil, errors.New(fmt.Sprint("tile at ", x, ",", y, " has flags that can not be saved in a JSON map"))
+			}
+			row[x] = jsonTile{cTile.Type.String(), cTile.Flags.Names()}
+		}
+		rows[y] = row
+	}
+	return rows, nil
+}
+
+// decodeJSONTiles checks the size of rows and calls setTile with each tile in them
+func decodeJSONTiles(rows [][]jsonTile, size [2]int, setTile func(pos [2]int, tileType tile.TileType, flags tile.TileFlag) error) error {
+	if len(rows) != size[1] {
+		return errors.New(fmt.Sprint("Error loading map: map has ", len(rows), " rows but a height of ", size[1]))
+	}
+	for y, row := range rows {
+		if len(row) != size[0] {
+			return errors.New(fmt.Sprint("Error loading map: row ", y, " has ", len(row), " tiles but the map has a width of ", size[0]))
+		}
+		for x, jTile := range row {
+			tileType, ok := tile.ParseTileType(jTile.Type)
+			if !ok {
+				return errors.New(fmt.Sprintf("Error loading map: unknown tile type %q at %d,%d", jTile.Type, x, y))
+			}
+			var flags tile.TileFlag
+			for _, name := range jTile.Flags {
+				flag, ok := tile.ParseTileFlag(name)
+				if !ok {
+					return errors.New(fmt.Sprintf("Error loading map: unknown flag %q at %d,%d", name, x, y))
+				}
+				flags |= flag
+			}
+			if err := setTile([2]int{x, y}, tileType, flags); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON replaces curMap with the map in data. The spawns in data are ignored as they
+// come from the tiles.
+func (curMap *Map) UnmarshalJSON(data []byte) error {
+	var jMap jsonMap
+	if err := json.Unmarshal(data, &jMap); err != nil {
+		return errors.New(fmt.Sprint("Error loading map: ", err))
+	}
+	if jMap.Version > jsonVersion {
+		return errors.New(fmt.Sprint("Error loading map: unsupported JSON map version ", jMap.Version))
+	}
+	if jMap.Width < 1 || jMap.Height < 1 || jMap.Width > MaxMapSize || jMap.Height > MaxMapSize {
+		return errors.New(fmt.Sprint("Error loading map: bad map size ", jMap.Width, "x", jMap.Height))
+	}
+
+	size := [2]int{jMap.Width, jMap.Height}
+	newMap := CreateEmptyMap(size)
+	err := decodeJSONTiles(jMap.Tiles, size, func(pos [2]int, tileType tile.TileType, flags tile.TileFlag) error {
+		cTile := &newMap.layers[LayerOf(tileType)][pos[0]][pos[1]]
+		cTile.Type = tileType
+		cTile.Flags = flags
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for name, rows := range jMap.Layers {
+		layer, ok := ParseLayer(name)
+		if !ok {
+			return errors.New(fmt.Sprintf("Error loading map: unknown layer %q", name))
+		}
+		newMap.layers[layer] = newLayer(size)
+		err := decodeJSONTiles(rows, size, func(pos [2]int, tileType tile.TileType, flags tile.TileFlag) error {
+			if !layer.Allows(tileType) {
+				return errors.New(fmt.Sprint("Error loading map: ", tileType, " at ", pos[0], ",", pos[1], " can not be on the ", layer, " layer"))
+			}
+			cTile := &newMap.layers[layer][pos[0]][pos[1]]
+			cTile.Type = tileType
+			cTile.Flags = flags
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	newMap.rebuildMoveMap()
+	for name, pos := range jMap.GhostSpawns {
+		personality, ok := ghost.ParsePersonality(name)
+		if !ok {
+			return errors.New(fmt.Sprintf("Error loading map: unknown ghost %q", name))
+		}
+		if !newMap.SetGhostSpawn(personality, pos) {
+			return errors.New(fmt.Sprint("Error loading map: ", personality, " spawns outside of the map"))
+		}
+	}
+	if jMap.Metadata != nil {
+		meta, err := jMap.Metadata.toMetadata()
+		if err != nil {
+			return err
+		}
+		if err := newMap.SetMetadata(meta); err != nil {
+			return err
+		}
+	}
+	newMap.Restart()
+	*curMap = newMap
+	return nil
+}
+
+func (meta *Metadata) toJSON() jsonMetadata {
+	jMeta := jsonMetadata{
+		Title:       meta.Title,
+		Author:      meta.Author,
+		Description: meta.Description,
+		Levels:      meta.Levels,
+		ParScore:    meta.ParScore,
+		Thumbnail:   meta.Thumbnail,
+	}
+	if !meta.Created.IsZero() {
+		jMeta.Created = meta.Created.Format(time.RFC3339)
+	}
+	if !meta.Modified.IsZero() {
+		jMeta.Modified = meta.Modified.Format(time.RFC3339)
+	}
+	return jMeta
+}
+
+func (jMeta *jsonMetadata) toMetadata() (Metadata, error) {
+	meta := Metadata{
+		Title:       jMeta.Title,
+		Author:      jMeta.Author,
+		Description: jMeta.Description,
+		Levels:      jMeta.Levels,
+		ParScore:    jMeta.ParScore,
+		Thumbnail:   jMeta.Thumbnail,
+	}
+	if meta.Title == "" {
+		meta.Title = jMeta.Name
+	}
+	var err error
+	if jMeta.Created != "" {
+		if meta.Created, err = time.Parse(time.RFC3339, jMeta.Created); err != nil {
+			return meta, errors.New(fmt.Sprint("Error loading map: bad created time: ", err))
+		}
+	}
+	if jMeta.Modified != "" {
+		if meta.Modified, err = time.Parse(time.RFC3339, jMeta.Modified); err != nil {
+			return meta, errors.New(fmt.Sprint("Error loading map: bad modified time: ", err))
+		}
+	}
+	return meta, nil
+}
@@ -0,0 +1,332 @@
+package maps
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunkink29/3dpacman/ghost"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// jsonTestMaps returns the shipped .tmap maps loaded, along with a small map that has ghost spawns
+// and metadata as the shipped maps have neither
+func jsonTestMaps(t *testing.T) map[string]*Map {
+	filenames, err := filepath.Glob("../assets/maps/*.tmap")
+	if err != nil || len(filenames) == 0 {
+		t.Fatal("no maps in ../assets/maps: ", err)
+	}
+	testMaps := make(map[string]*Map)
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		curMap, err := LoadMap(data)
+		if err != nil {
+			t.Fatal(filename, ": ", err)
+		}
+		testMaps[filepath.Base(filename)] = curMap
+	}
+
+	curMap := CreateEmptyMap([2]int{5, 4})
+	curMap.PlaceTile(Walls, [2]int{0, 0}, tile.Wall, tile.Right)
+	curMap.PlaceTile(Walls, [2]int{1, 0}, tile.Wall, tile.Left)
+	curMap.PlaceTile(Pickups, [2]int{2, 1}, tile.DotBig, 0)
+	curMap.PlaceTile(Pickups, [2]int{3, 1}, tile.Dot, 0)
+	curMap.ChangeMapTile([2]int{2, 2}, tile.PlayerSpawn, 0)
+	curMap.SetGhostSpawn(ghost.Blinky, [2]int{1, 3})
+	curMap.SetGhostSpawn(ghost.Clyde, [2]int{4, 3})
+	meta := curMap.GetMetadata()
+	meta.Title = "Test"
+	meta.Author = "Tester"
+	meta.Description = "A map for the JSON tests"
+	meta.Created = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	meta.Modified = time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	meta.Levels = []int{1, 3}
+	meta.ParScore = 12345
+	if err := curMap.SetMetadata(meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := curMap.SetThumbnail(image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	testMaps["metadata"] = &curMap
+	return testMaps
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for name, curMap := range jsonTestMaps(t) {
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(curMap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var newMap Map
+			if err := json.Unmarshal(data, &newMap); err != nil {
+				t.Fatal(err)
+			}
+
+			size := curMap.GetSize()
+			if newSize := newMap.GetSize(); newSize != size {
+				t.Fatalf("size is %v, want %v", newSize, size)
+			}
+			for layer := Layer(0); layer < NumLayers; layer++ {
+				for x := 0; x < size[0]; x++ {
+					for y := 0; y < size[1]; y++ {
+						pos := [2]int{x, y}
+						want, got := curMap.GetLayerTile(layer, pos), newMap.GetLayerTile(layer, pos)
+						if got.Type != want.Type || got.Flags != want.Flags {
+							t.Fatalf("%v tile at %v is %v %v, want %v %v", layer, pos, got.Type, got.Flags, want.Type, want.Flags)
+						}
+					}
+				}
+			}
+
+			if got, want := newMap.GetPlayerSpawn(), curMap.GetPlayerSpawn(); got != want {
+				t.Errorf("player spawn is %v, want %v", got, want)
+			}
+			if got, want := newMap.GetGhostSpawn(), curMap.GetGhostSpawn(); got != want {
+				t.Errorf("ghost spawn is %v, want %v", got, want)
+			}
+			gotDoor, gotExit, gotOk := newMap.GetGhostDoor()
+			wantDoor, wantExit, wantOk := curMap.GetGhostDoor()
+			if gotDoor != wantDoor || gotExit != wantExit || gotOk != wantOk {
+				t.Errorf("ghost door is %v %v %v, want %v %v %v", gotDoor, gotExit, gotOk, wantDoor, wantExit, wantOk)
+			}
+			gotFruit, gotOk := newMap.GetFruitSpawn()
+			wantFruit, wantOk := curMap.GetFruitSpawn()
+			if gotFruit != wantFruit || gotOk != wantOk {
+				t.Errorf("fruit spawn is %v %v, want %v %v", gotFruit, gotOk, wantFruit, wantOk)
+			}
+			if got, want := newMap.GetGhostSpawns(), curMap.GetGhostSpawns(); !reflect.DeepEqual(got, want) {
+				t.Errorf("ghost spawns are %v, want %v", got, want)
+			}
+
+			got, want := newMap.GetMetadata(), curMap.GetMetadata()
+			if !got.Created.Equal(want.Created) || !got.Modified.Equal(want.Modified) {
+				t.Errorf("times are %v %v, want %v %v", got.Created, got.Modified, want.Created, want.Modified)
+			}
+			got.Created, got.Modified, want.Created, want.Modified = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("metadata is %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schemaData, err := ioutil.ReadFile("map.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(schemaData, &schema); err != nil {
+		t.Fatal(err)
+	}
+	for name, curMap := range jsonTestMaps(t) {
+		data, err := json.Marshal(curMap)
+		if err != nil {
+			t.Fatal(name, ": ", err)
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(name, ": ", err)
+		}
+		if err := validateSchema(schema, schema, doc, "$"); err != nil {
+			t.Error(name, ": ", err)
+		}
+	}
+
+	// make sure the validator is not letting everything through
+	bad := []string{
+		`{"version": 2, "width": 1, "height": 1, "tiles": [[{"type": "blank"}]]}`,
+		`{"version": 1, "width": 1, "height": 1}`,
+		`{"version": 1, "width": 1, "height": 1, "tiles": [[{"type": "lava"}]]}`,
+		`{"version": 1, "width": 1, "height": 1, "tiles": [[{"type": "wall", "flags": ["up", "up"]}]]}`,
+		`{"version": 1, "width": 1, "height": 1, "tiles": [[{"type": "blank"}]], "ghostSpawns": {"blinky": [1]}}`,
+		`{"version": 1, "width": 1, "height": 1, "tiles": [[{"type": "blank"}]], "layers": {"sky": []}}`,
+		`{"version": 1, "width": 1, "height": 1, "tiles": [[{"type": "blank"}]], "extra": true}`,
+	}
+	for _, data := range bad {
+		var doc interface{}
+		if err := json.Unmarshal([]byte(data), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if validateSchema(schema, schema, doc, "$") == nil {
+			t.Error("invalid map passed the schema: ", data)
+		}
+	}
+}
+
+// keywords of the schema that only describe a value
+var schemaAnnotations = map[string]bool{
+	"$schema": true, "$id": true, "title": true, "description": true, "definitions": true,
+	"format": true, "readOnly": true, "contentEncoding": true, "contentMediaType": true,
+}
+
+// validateSchema checks value against schema, root being the schema refs are resolved in. Only the
+// keywords map.schema.json uses are supported so any other keyword is reported as an error.
+func validateSchema(root map[string]interface{}, schema map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		definitions, _ := root["definitions"].(map[string]interface{})
+		definition, ok := definitions[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: unknown ref %q", path, ref)
+		}
+		return validateSchema(root, definition, value, path)
+	}
+	object, _ := value.(map[string]interface{})
+	array, _ := value.([]interface{})
+	for keyword, arg := range schema {
+		switch keyword {
+		case "type":
+			if !schemaType(arg.(string), value) {
+				return fmt.Errorf("%s: %v is not of type %s", path, value, arg)
+			}
+		case "const":
+			if !reflect.DeepEqual(arg, value) {
+				return fmt.Errorf("%s: %v is not %v", path, value, arg)
+			}
+		case "enum":
+			found := false
+			for _, option := range arg.([]interface{}) {
+				found = found || reflect.DeepEqual(option, value)
+			}
+			if !found {
+				return fmt.Errorf("%s: %v is not one of %v", path, value, arg)
+			}
+		case "minimum":
+			if number, ok := value.(float64); ok && number < arg.(float64) {
+				return fmt.Errorf("%s: %v is less than %v", path, number, arg)
+			}
+		case "maximum":
+			if number, ok := value.(float64); ok && number > arg.(float64) {
+				return fmt.Errorf("%s: %v is more than %v", path, number, arg)
+			}
+		case "minItems":
+			if array != nil && float64(len(array)) < arg.(float64) {
+				return fmt.Errorf("%s: %d items is fewer than %v", path, len(array), arg)
+			}
+		case "maxItems":
+			if array != nil && float64(len(array)) > arg.(float64) {
+				return fmt.Errorf("%s: %d items is more than %v", path, len(array), arg)
+			}
+		case "uniqueItems":
+			for i := range array {
+				for j := i + 1; j < len(array) && arg.(bool); j++ {
+					if reflect.DeepEqual(array[i], array[j]) {
+						return fmt.Errorf("%s: items %d and %d are the same", path, i, j)
+					}
+				}
+			}
+		case "items":
+			for i, item := range array {
+				if err := validateSchema(root, arg.(map[string]interface{}), item, fmt.Sprint(path, "[", i, "]")); err != nil {
+					return err
+				}
+			}
+		case "required":
+			for _, name := range arg.([]interface{}) {
+				if _, ok := object[name.(string)]; object != nil && !ok {
+					return fmt.Errorf("%s: missing %q", path, name)
+				}
+			}
+		case "propertyNames":
+			for name := range object {
+				if err := validateSchema(root, arg.(map[string]interface{}), name, path+"."+name); err != nil {
+					return err
+				}
+			}
+		case "properties", "additionalProperties":
+			// checked together below
+		default:
+			if !schemaAnnotations[keyword] {
+				return fmt.Errorf("%s: unsupported schema keyword %q", path, keyword)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, propValue := range object {
+		propPath := path + "." + name
+		if propSchema, ok := properties[name].(map[string]interface{}); ok {
+			if err := validateSchema(root, propSchema, propValue, propPath); err != nil {
+				return err
+			}
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: unexpected property", propPath)
+			}
+		case map[string]interface{}:
+			if err := validateSchema(root, additional, propValue, propPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// schemaType returns if value decoded from JSON is of the schema type name
+func schemaType(name string, value interface{}) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == float64(int64(number))
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func TestJSONGhostSpawnOutside(t *testing.T) {
+	curMap := CreateEmptyMap([2]int{5, 4})
+	data, err := json.Marshal(&curMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, pos := range [][2]int{{5, -3}, {-1, 0}, {5, 0}, {0, 4}} {
+		doc["ghostSpawns"] = map[string][2]int{"blinky": pos}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var newMap Map
+		if err := json.Unmarshal(data, &newMap); err == nil {
+			t.Errorf("map with a ghost spawn on %v loaded without an error", pos)
+		}
+	}
+
+	if curMap.SetGhostSpawn(ghost.Pinky, [2]int{5, -3}) || len(curMap.GetGhostSpawns()) != 0 {
+		t.Error("ghost spawn outside of the map was set")
+	}
+}
@@ -0,0 +1,147 @@
+package maps
+
+import "github.com/sunkink29/3dpacman/tile"
+
+// Layer is one of the grids of tiles a map is made of. Every position on the map has a tile on
+// each layer, most of them blank.
+type Layer int
+
+const (
+	// Floor holds the ground under everything else: tunnels and the ghost house
+	Floor Layer = iota
+	// Walls holds walls and ghost house doors
+	Walls
+	// Pickups holds the dots and big dots the player eats
+	Pickups
+	// Decoration holds tiles of any type that are only drawn and have no effect on the game
+	Decoration
+	// Triggers holds the spawns that say where the player and bonus fruit appear
+	Triggers
+	// NumLayers is the number of layers, keep this last
+	NumLayers
+)
+
+// Collision says how the tiles on a layer affect the player and ghosts
+type Collision int
+
+const (
+	// CollideNone tiles are ignored by the game
+	CollideNone Collision = iota
+	// CollideGround tiles change how things on them move: tunnels lead to the other side of the
+	// map and slow ghosts down and the ghost house holds ghosts until they are released
+	CollideGround
+	// CollideSolid tiles block movement, ghost house doors only let ghosts leaving the house through
+	CollideSolid
+	// CollidePickup tiles are eaten when the player moves onto them
+	CollidePickup
+	// CollideTrigger tiles do not block anything, they mark where things start or appear
+	CollideTrigger
+)
+
+type layerInfo struct {
+	name string
+	// layers are drawn from the lowest render order to the highest so later ones cover earlier ones
+	renderOrder int
+	collision   Collision
+	// the tile types the layer can hold besides blank, nil for any type
+	types []tile.TileType
+}
+
+var layerInfos = [NumLayers]layerInfo{
+	Floor:      {"floor", 0, CollideGround, []tile.TileType{tile.Tunnel, tile.GhostHouse}},
+	Walls:      {"walls", 4, CollideSolid, []tile.TileType{tile.Wall, tile.GhostDoor}},
+	Pickups:    {"pickups", 3, CollidePickup, []tile.TileType{tile.Dot, tile.DotBig}},
+	Decoration: {"decoration", 1, CollideNone, nil},
+	Triggers:   {"triggers", 2, CollideTrigger, []tile.TileType{tile.PlayerSpawn, tile.FruitSpawn}},
+}
+
+// IsValid returns true if layer is one of the layers above
+func (layer Layer) IsValid() bool {
+	return layer >= 0 && layer < NumLayers
+}
+
+func (layer Layer) String() string {
+	if !layer.IsValid() {
+		return "unknown"
+	}
+	return layerInfos[layer].name
+}
+
+// ParseLayer returns the layer with the given name
+func ParseLayer(name string) (Layer, bool) {
+	for i, info := range layerInfos {
+		if info.name == name {
+			return Layer(i), true
+		}
+	}
+	return Floor, false
+}
+
+// GetCollision returns how the tiles on the layer affect the player and ghosts
+func (layer Layer) GetCollision() Collision {
+	return layerInfos[layer].collision
+}
+
+// Allows returns true if the layer can hold tiles of tileType
+func (layer Layer) Allows(tileType tile.TileType) bool {
+	if tileType == tile.Blank || layerInfos[layer].types == nil {
+		return tileType.IsValid()
+	}
+	for _, allowed := range layerInfos[layer].types {
+		if allowed == tileType {
+			return true
+		}
+	}
+	return false
+}
+
+// LayerOf returns the layer tiles of tileType are placed on when no layer is chosen. Types that
+// only belong on decoration, such as the player and ghost textures, return Decoration.
+func LayerOf(tileType tile.TileType) Layer {
+	for layer := Layer(0); layer < NumLayers; layer++ {
+		if layerInfos[layer].types != nil && tileType != tile.Blank && layer.Allows(tileType) {
+			return layer
+		}
+	}
+	if tileType == tile.Blank {
+		return Floor
+	}
+	return Decoration
+}
+
+// RenderOrder returns the layers in the order they are drawn, bottom first
+func RenderOrder() []Layer {
+	layers := make([]Layer, NumLayers)
+	for layer := range layers {
+		layers[layerInfos[layer].renderOrder] = Layer(layer)
+	}
+	return layers
+}
+
+// extraLayers returns the layers that can not be worked out from the tiles returned by GetMapTile
+// by putting each of them on the layer its type belongs to. Maps made without choosing layers have
+// none. Flags are only compared if withFlags is true.
+func (curMap *Map) extraLayers(withFlags bool) []Layer {
+	var layers []Layer
+	for layer := Layer(0); layer < NumLayers; layer++ {
+		if !curMap.layerMatchesTiles(layer, withFlags) {
+			layers = append(layers, layer)
+		}
+	}
+	return layers
+}
+
+func (curMap *Map) layerMatchesTiles(layer Layer, withFlags bool) bool {
+	for i, col := range curMap.layers[layer] {
+		for j, cTile := range col {
+			expected := curMap.GetMapTile([2]int{i, j})
+			if LayerOf(expected.Type) != layer {
+				expected.Type = tile.Blank
+			}
+			if cTile.Type != expected.Type || withFlags && cTile.Flags != expected.Flags && cTile.Type != tile.Blank {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -1,77 +1,223 @@
 package maps
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"math"
+	"math/rand"
 	"strings"
 
-	"github.com/go-gl/glfw/v3.2/glfw"
-	"github.com/sqweek/dialog"
-
-	"github.com/sunkink29/3dpacman/input"
+	"github.com/sunkink29/3dpacman/game"
+	"github.com/sunkink29/3dpacman/ghost"
+	"github.com/sunkink29/3dpacman/movement"
 	"github.com/sunkink29/3dpacman/player"
-	"github.com/sunkink29/3dpacman/rendering"
 	"github.com/sunkink29/3dpacman/tile"
 )
 
-// in the movement map each point is stored as binary where the first is up, the second is down
-// third is left and the forth is right
-// ex: 0110 is a point where you can move down and left
 type Map struct {
-	size      [2]int32
-	tMap      [][]tile.Tile // tile map: array that holds the tile position and texture options
+	size [2]int32
+	// tile map for each layer: arrays that hold the tile position and texture options
+	layers [NumLayers][][]tile.Tile
+	// movement map: the directions that can be moved in from each tile, kept up to date with the
+	// walls and floor layers.
+	// each point is stored as binary where the first is up, the second is down
+	// third is left and the forth is right
+	// ex: 0110 is a point where you can move down and left
+	moveMap   movement.MoveMap
 	playerObj player.Player
+	ghosts    []ghost.Ghost
+	modes     ghost.ModeScheduler
+	rng       *rand.Rand
+	house     ghost.House
+	fruit     game.FruitSpawner
+	state     game.State
+	// the last tile the player was seen on, used to detect when the player moves onto a new tile
+	lastPlayerPos [2]int
+	// the dots and big dots on the map when the level started, with the changes made to them since
+	// by PlaceTile, put back when the level is cleared
+	levelDots [][]tile.TileType
+	// the file the map was loaded from or saved to
+	filename string
+	// tiles set by the map for ghosts to start on instead of the ghost house
+	ghostSpawns map[ghost.Personality][2]int
+	// the title, author and other details shown by the level select screen and map tools
+	metadata Metadata
+	// how the map is packed and checksummed when it is saved to a .tmap file
+	fileOptions FileOptions
 }
 
-func (curMap *Map) Render(deltaTime float64) {
-	for _, col := range curMap.tMap {
-		for _, row := range col {
-			row.Render()
-		}
+const defaultSeed = 1
+
+func CreateEmptyMap(size [2]int) Map {
+	size32 := [2]int32{int32(size[0]), int32(size[1])}
+	playerStart := [2]int{2, 1}
+	newMap := Map{
+		size:          size32,
+		playerObj:     player.New(playerStart),
+		rng:           rand.New(rand.NewSource(defaultSeed)),
+		state:         game.NewState(0),
+		lastPlayerPos: playerStart,
+	}
+	for layer := range newMap.layers {
+		newMap.layers[layer] = newLayer(size)
+	}
+	newMap.rebuildMoveMap()
+	ghostSpawn := newMap.GetGhostSpawn()
+	newMap.ghosts = []ghost.Ghost{
+		ghost.New(ghost.Blinky, ghostSpawn),
+		ghost.New(ghost.Pinky, ghostSpawn),
+		ghost.New(ghost.Inky, ghostSpawn),
+		ghost.New(ghost.Clyde, ghostSpawn),
 	}
-	curMap.playerObj.Render(deltaTime)
+	newMap.placeGhosts()
+	newMap.applyLevel()
+	newMap.snapshotDots()
+	return newMap
 }
 
-func CreateEmptyMap(size [2]int) Map {
+// newLayer returns a layer of blank tiles
+func newLayer(size [2]int) [][]tile.Tile {
 	tiles := make([][]tile.Tile, size[0])
-	mMap := make([][]int, size[0])
-
-	toggle := false
 	for colIndex := range tiles {
 		col := make([]tile.Tile, size[1])
 		for rowIndex := range col {
 			col[rowIndex] = tile.NewTile([2]int{colIndex, rowIndex}, 0, 0, 0)
-			toggle = !toggle
 		}
 		tiles[colIndex] = col
-		mMap[colIndex] = make([]int, size[1])
 	}
-
-	size32 := [2]int32{int32(size[0]), int32(size[1])}
-	return Map{size32, tiles, player.New([2]int{2, 1})}
+	return tiles
 }
 
+// the layers GetMapTile looks through, decoration is left out as it does not change the game
+var gameLayers = [...]Layer{Walls, Pickups, Triggers, Floor}
+
+// GetMapTile returns the tile the game sees at pos, the first tile that is not blank on the walls,
+// pickups, triggers and floor layers
 func (curMap *Map) GetMapTile(pos [2]int) tile.Tile {
-	return curMap.tMap[pos[0]][pos[1]]
+	for _, layer := range gameLayers {
+		if cTile := curMap.layers[layer][pos[0]][pos[1]]; cTile.Type != tile.Blank {
+			return cTile
+		}
+	}
+	return curMap.layers[Floor][pos[0]][pos[1]]
+}
+
+// GetLayerTile returns the tile at pos on a layer
+func (curMap *Map) GetLayerTile(layer Layer, pos [2]int) tile.Tile {
+	return curMap.layers[layer][pos[0]][pos[1]]
 }
 
 func (curMap *Map) GetSize() [2]int {
 	return [2]int{int(curMap.size[0]), int(curMap.size[1])}
 }
 
-func (curMap *Map) ChangeMapTile(cTile *tile.Tile, tileType tile.TileType, flags tile.TileFlag) {
+// ChangeMapTile replaces every tile at pos with a tile on the layer its type belongs to
+func (curMap *Map) ChangeMapTile(pos [2]int, tileType tile.TileType, flags tile.TileFlag) {
+	target := LayerOf(tileType)
+	for layer := Layer(0); layer < NumLayers; layer++ {
+		if layer != target {
+			curMap.SetTile(layer, pos, tile.Blank, 0)
+		}
+	}
+	curMap.SetTile(target, pos, tileType, flags)
+}
+
+// SetTile replaces the tile at pos on a layer and returns false if the layer can not hold tiles
+// of tileType. Walls without direction flags are connected to the walls next to them.
+func (curMap *Map) SetTile(layer Layer, pos [2]int, tileType tile.TileType, flags tile.TileFlag) bool {
+	if !curMap.PlaceTile(layer, pos, tileType, flags) {
+		return false
+	}
+	if layer.GetCollision() == CollideSolid && (tileType == tile.Wall && flags&tile.All == 0 || tileType != tile.Wall) {
+		curMap.updateNearbyWall(&curMap.layers[layer][pos[0]][pos[1]])
+	}
+	return true
+}
+
+// PlaceTile replaces the tile at pos on a layer with exactly the given type and flags, the tiles
+// next to it are left as they are. It returns false if the layer can not hold tiles of tileType.
+func (curMap *Map) PlaceTile(layer Layer, pos [2]int, tileType tile.TileType, flags tile.TileFlag) bool {
+	if !layer.IsValid() || !layer.Allows(tileType) {
+		return false
+	}
+	cTile := &curMap.layers[layer][pos[0]][pos[1]]
+	if layer.GetCollision() == CollidePickup {
+		if isDot(cTile.Type) {
+			curMap.state.DotsLeft--
+		}
+		if isDot(tileType) {
+			curMap.state.DotsLeft++
+		}
+		// dots placed while playing on a map without a file come back with the rest at the next level
+		if pos[0] < len(curMap.levelDots) && pos[1] < len(curMap.levelDots[pos[0]]) {
+			curMap.levelDots[pos[0]][pos[1]] = tile.Blank
+			if isDot(tileType) {
+				curMap.levelDots[pos[0]][pos[1]] = tileType
+			}
+		}
+	}
 	cTile.Type = tileType
 	cTile.Flags = flags
-	if cTile.Type == tile.Wall && cTile.Flags&tile.All == 0 || cTile.Type != tile.Wall {
-		curMap.updateNearbyWall(cTile)
+	switch layer.GetCollision() {
+	case CollideSolid, CollideGround:
+		curMap.moveMap.Update(pos, curMap.getTileType)
+	}
+	return true
+}
+
+// SetTileFlags changes the flags of the tile at pos on a layer without connecting walls
+func (curMap *Map) SetTileFlags(layer Layer, pos [2]int, flags tile.TileFlag) {
+	curMap.layers[layer][pos[0]][pos[1]].Flags = flags
+}
+
+// getTileType returns the type of tile that decides how things move at pos, the wall or door on
+// the walls layer if there is one and the floor under it otherwise
+// inBounds returns true if pos is a tile of the map
+func (curMap *Map) inBounds(pos [2]int) bool {
+	return pos[0] >= 0 && pos[1] >= 0 && pos[0] < int(curMap.size[0]) && pos[1] < int(curMap.size[1])
+}
+
+func (curMap *Map) getTileType(pos [2]int) tile.TileType {
+	if wallType := curMap.layers[Walls][pos[0]][pos[1]].Type; wallType != tile.Blank {
+		return wallType
 	}
+	return curMap.layers[Floor][pos[0]][pos[1]].Type
+}
+
+// GetMoveMap returns the directions that can be moved in from each tile
+func (curMap *Map) GetMoveMap() *movement.MoveMap {
+	return &curMap.moveMap
+}
+
+// rebuildMoveMap works out the whole movement map again, used after the tiles are changed without ChangeMapTile
+func (curMap *Map) rebuildMoveMap() {
+	curMap.moveMap = movement.BuildMoveMap(curMap.GetSize(), curMap.getTileType)
+}
+
+func isDot(tileType tile.TileType) bool {
+	return tileType == tile.Dot || tileType == tile.DotBig
+}
+
+// countDots returns the number of dots and big dots left on the map
+func (curMap *Map) countDots() int {
+	count := 0
+	for _, col := range curMap.layers[Pickups] {
+		for _, curTile := range col {
+			if isDot(curTile.Type) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// GetState returns the score and progress of the game being played on the map
+func (curMap *Map) GetState() *game.State {
+	return &curMap.state
 }
 
 func (curMap *Map) GetPlayerSpawn() [2]int {
-	for i, col := range curMap.tMap {
+	for i, col := range curMap.layers[Triggers] {
 		for j, curTile := range col {
 			if curTile.Type == tile.PlayerSpawn {
 				return [2]int{i, j}
@@ -81,13 +227,186 @@ func (curMap *Map) GetPlayerSpawn() [2]int {
 	return [2]int{2, 2}
 }
 
+// GetFruitSpawn returns the tile bonus fruit appear on and false if the map has none
+func (curMap *Map) GetFruitSpawn() ([2]int, bool) {
+	for i, col := range curMap.layers[Triggers] {
+		for j, curTile := range col {
+			if curTile.Type == tile.FruitSpawn {
+				return [2]int{i, j}, true
+			}
+		}
+	}
+	return [2]int{0, 0}, false
+}
+
+// IsFruitActive returns true while a bonus fruit is waiting to be eaten
+func (curMap *Map) IsFruitActive() bool {
+	return curMap.fruit.IsActive()
+}
+
+// GetGhostSpawn returns the open tile closest to the centre of the map
+func (curMap *Map) GetGhostSpawn() [2]int {
+	center := [2]int{int(curMap.size[0]) / 2, int(curMap.size[1]) / 2}
+	spawn := center
+	bestDist := -1
+	for i, col := range curMap.layers[Walls] {
+		for j, curTile := range col {
+			if curTile.Type == tile.Wall {
+				continue
+			}
+			dist := (i-center[0])*(i-center[0]) + (j-center[1])*(j-center[1])
+			if bestDist == -1 || dist < bestDist {
+				spawn = [2]int{i, j}
+				bestDist = dist
+			}
+		}
+	}
+	return spawn
+}
+
+// GetGhostDoor returns the first ghost house door on the map and the open tile outside of it
+// that ghosts leaving the house head for
+func (curMap *Map) GetGhostDoor() (door [2]int, exit [2]int, ok bool) {
+	for i, col := range curMap.layers[Walls] {
+		for j, curTile := range col {
+			if curTile.Type != tile.GhostDoor {
+				continue
+			}
+			door = [2]int{i, j}
+			for _, dir := range [4][2]int{{0, -1}, {0, 1}, {-1, 0}, {1, 0}} {
+				exit = [2]int{i + dir[0], j + dir[1]}
+				if exit[0] < 0 || exit[1] < 0 || exit[0] >= int(curMap.size[0]) || exit[1] >= int(curMap.size[1]) {
+					continue
+				}
+				switch curMap.getTileType(exit) {
+				case tile.Wall, tile.GhostHouse, tile.GhostDoor:
+					continue
+				}
+				return door, exit, true
+			}
+		}
+	}
+	return door, exit, false
+}
+
+// placeGhosts gives every ghost its home. Blinky starts outside the ghost house door and the others
+// are spread over the ghost house tiles. Maps without a ghost house and door start every ghost on
+// the open tile closest to the centre. Ghosts given a spawn by SetGhostSpawn start there instead.
+func (curMap *Map) placeGhosts() {
+	curMap.placeHouseGhosts()
+	curMap.placeSpawnedGhosts()
+}
+
+func (curMap *Map) placeHouseGhosts() {
+	var houseTiles [][2]int
+	for i, col := range curMap.layers[Floor] {
+		for j, curTile := range col {
+			if curTile.Type == tile.GhostHouse {
+				houseTiles = append(houseTiles, [2]int{i, j})
+			}
+		}
+	}
+	_, exit, hasDoor := curMap.GetGhostDoor()
+	if len(houseTiles) == 0 || !hasDoor {
+		spawn := curMap.GetGhostSpawn()
+		for i := range curMap.ghosts {
+			curMap.ghosts[i].SetHome(spawn, spawn, false)
+		}
+		return
+	}
+	for i := range curMap.ghosts {
+		if curMap.ghosts[i].GetPersonality() == ghost.Blinky {
+			curMap.ghosts[i].SetHome(exit, exit, false)
+			continue
+		}
+		home := houseTiles[i*len(houseTiles)/len(curMap.ghosts)]
+		curMap.ghosts[i].SetHome(home, exit, true)
+	}
+}
+
+// SetGhostSpawn makes a ghost start on pos instead of being placed by the ghost house and returns
+// false if pos is outside of the map. The ghost waits to be released if pos is a ghost house tile.
+func (curMap *Map) SetGhostSpawn(personality ghost.Personality, pos [2]int) bool {
+	if !curMap.inBounds(pos) {
+		return false
+	}
+	if curMap.ghostSpawns == nil {
+		curMap.ghostSpawns = make(map[ghost.Personality][2]int)
+	}
+	curMap.ghostSpawns[personality] = pos
+	curMap.placeGhosts()
+	return true
+}
+
+// ClearGhostSpawn makes a ghost placed by the ghost house again
+func (curMap *Map) ClearGhostSpawn(personality ghost.Personality) {
+	delete(curMap.ghostSpawns, personality)
+	curMap.placeGhosts()
+}
+
+// GetGhostSpawns returns the tiles set by SetGhostSpawn
+func (curMap *Map) GetGhostSpawns() map[ghost.Personality][2]int {
+	spawns := make(map[ghost.Personality][2]int, len(curMap.ghostSpawns))
+	for personality, pos := range curMap.ghostSpawns {
+		spawns[personality] = pos
+	}
+	return spawns
+}
+
+// Restart starts the game over from the first level using the tiles currently on the map
+func (curMap *Map) Restart() {
+	curMap.snapshotDots()
+	curMap.state = game.NewState(curMap.countDots())
+	curMap.placeGhosts()
+	curMap.applyLevel()
+	curMap.resetPositions()
+}
+
+// placeSpawnedGhosts moves the ghosts given a spawn by SetGhostSpawn to it
+func (curMap *Map) placeSpawnedGhosts() {
+	_, exit, hasDoor := curMap.GetGhostDoor()
+	for i := range curMap.ghosts {
+		spawn, ok := curMap.ghostSpawns[curMap.ghosts[i].GetPersonality()]
+		if !ok || spawn[0] < 0 || spawn[1] < 0 || spawn[0] >= int(curMap.size[0]) || spawn[1] >= int(curMap.size[1]) {
+			continue
+		}
+		inHouse := hasDoor && curMap.layers[Floor][spawn[0]][spawn[1]].Type == tile.GhostHouse
+		if !inHouse {
+			exit = spawn
+		}
+		curMap.ghosts[i].SetHome(spawn, exit, inHouse)
+	}
+}
+
+func (curMap *Map) GetPlayer() *player.Player {
+	return &curMap.playerObj
+}
+
+func (curMap *Map) GetGhosts() []ghost.Ghost {
+	return curMap.ghosts
+}
+
+func (curMap *Map) GetGhostMode() ghost.Mode {
+	return curMap.modes.GetMode()
+}
+
+// GetModeTimeLeft returns the seconds left in the current ghost mode or -1 if it lasts for the rest of the level
+func (curMap *Map) GetModeTimeLeft() float64 {
+	return curMap.modes.GetTimeLeft()
+}
+
+// SetSeed reseeds the random number generator frightened ghosts use to pick their turns
+func (curMap *Map) SetSeed(seed int64) {
+	curMap.rng = rand.New(rand.NewSource(seed))
+}
+
 func (curMap *Map) updateNearbyWall(cTile *tile.Tile) {
 	deleteWall := tile.TileFlag(0)
 	if cTile.Type == tile.Wall && cTile.Flags&tile.All == 0 {
 		deleteWall = tile.All
 	}
 	if cTile.Pos[1] > 0 {
-		top := &curMap.tMap[int(cTile.Pos[0])][int(cTile.Pos[1])-1]
+		top := &curMap.layers[Walls][int(cTile.Pos[0])][int(cTile.Pos[1])-1]
 		if top.Type == tile.Wall {
 			cTile.Flags |= tile.Up & deleteWall
 			top.Flags |= tile.Down
@@ -95,7 +414,7 @@ func (curMap *Map) updateNearbyWall(cTile *tile.Tile) {
 		}
 	}
 	if int32(cTile.Pos[1]) < curMap.size[1]-1 {
-		bottem := &curMap.tMap[int(cTile.Pos[0])][int(cTile.Pos[1])+1]
+		bottem := &curMap.layers[Walls][int(cTile.Pos[0])][int(cTile.Pos[1])+1]
 		if bottem.Type == tile.Wall {
 			cTile.Flags |= tile.Down & deleteWall
 			bottem.Flags |= tile.Up
@@ -103,7 +422,7 @@ func (curMap *Map) updateNearbyWall(cTile *tile.Tile) {
 		}
 	}
 	if cTile.Pos[0] > 0 {
-		left := &curMap.tMap[int(cTile.Pos[0])-1][int(cTile.Pos[1])]
+		left := &curMap.layers[Walls][int(cTile.Pos[0])-1][int(cTile.Pos[1])]
 		if left.Type == tile.Wall {
 			cTile.Flags |= tile.Left & deleteWall
 			left.Flags |= tile.Right
@@ -111,7 +430,7 @@ func (curMap *Map) updateNearbyWall(cTile *tile.Tile) {
 		}
 	}
 	if int32(cTile.Pos[0]) < curMap.size[0]-1 {
-		right := &curMap.tMap[int(cTile.Pos[0])+1][int(cTile.Pos[1])]
+		right := &curMap.layers[Walls][int(cTile.Pos[0])+1][int(cTile.Pos[1])]
 		if right.Type == tile.Wall {
 			cTile.Flags |= tile.Right & deleteWall
 			right.Flags |= tile.Left
@@ -125,207 +444,288 @@ func (curMap *Map) SaveToFile(filename string) error {
 		filename += ".tmap"
 	}
 
-	data := []byte("tmap")
-	bs := make([]byte, 4)
-	binary.LittleEndian.PutUint32(bs, uint32(curMap.size[0]))
-	data = append(data, bs...)
-
-	binary.LittleEndian.PutUint32(bs, uint32(curMap.size[1]))
-	data = append(data, bs...)
-
-	for _, col := range curMap.tMap {
-		for _, cTile := range col {
-			bs := make([]byte, 2)
-			binary.LittleEndian.PutUint16(bs, uint16(cTile.Type))
-			data = append(data, bs...)
+	if err := curMap.writeFile(filename); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(moveMapFilename(filename), curMap.encodeMoveMap(), 0644); err != nil {
+		return errors.New(fmt.Sprint("Error Saving Movement Map to file:", err))
+	}
+	curMap.filename = filename
+	return nil
+}
 
-			binary.LittleEndian.PutUint16(bs, uint16(cTile.Flags))
-			data = append(data, bs...)
-		}
+// writeFile saves the map to filename the way SaveToFile does without the movement map
+func (curMap *Map) writeFile(filename string) error {
+	if err := curMap.metadata.check(); err != nil {
+		return err
 	}
+	curMap.touchMetadata()
+	data := curMap.encode()
 	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
 		return errors.New(fmt.Sprint("Error Saving Map to file:", err))
 	}
 	return nil
 }
 
+// LoadMapFromFile reads a map saved by SaveToFile. Maps saved in older versions of the format are
+// upgraded and written in the current format the next time they are saved.
+// The movement map is read from the .pmap file next to the map if it was saved with the same tiles
+// and worked out from the tiles otherwise.
 func LoadMapFromFile(filename string) (*Map, error) {
-	const SIZEOF_INT32 = 4
-	const SIZEOF_INT16 = 2
-	const SIZEOF_TYPESTRING = len("tmap")
-
 	mapBytes, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, errors.New(fmt.Sprint("Error Reading Map:", err))
 	}
-	mapBytes = mapBytes[SIZEOF_TYPESTRING:]
-
-	var mapSize [2]int
-	mapSize[0] = int(binary.LittleEndian.Uint32(mapBytes[0:SIZEOF_INT32]))
-	mapSize[1] = int(binary.LittleEndian.Uint32(mapBytes[SIZEOF_INT32 : SIZEOF_INT32*2]))
+	newMap, err := decodeMap(mapBytes)
+	if err != nil {
+		return nil, err
+	}
+	if moveBytes, err := ioutil.ReadFile(moveMapFilename(filename)); err != nil || !newMap.decodeMoveMap(moveBytes) {
+		newMap.rebuildMoveMap()
+	}
+	newMap.filename = filename
+	newMap.Restart()
+	return newMap, nil
+}
 
-	mapBytes = mapBytes[SIZEOF_INT32*2:]
+// LoadMap reads a map from the contents of a .tmap file.
+// If the data is malformed the error is a *LoadError.
+func LoadMap(data []byte) (*Map, error) {
+	newMap, err := decodeMap(data)
+	if err != nil {
+		return nil, err
+	}
+	newMap.rebuildMoveMap()
+	newMap.Restart()
+	return newMap, nil
+}
 
-	if len(mapBytes) == mapSize[0]*mapSize[1]*SIZEOF_INT32 {
-		newMap := CreateEmptyMap(mapSize)
-		for i, col := range newMap.tMap {
-			for j := range col {
-				curIndex := (i*mapSize[1] + j)
-				newMap.tMap[i][j].Type = tile.TileType(binary.LittleEndian.Uint16(mapBytes[curIndex*SIZEOF_INT32 : curIndex*SIZEOF_INT32+SIZEOF_INT16]))
-				newMap.tMap[i][j].Flags = tile.TileFlag(binary.LittleEndian.Uint16(mapBytes[curIndex*SIZEOF_INT32+SIZEOF_INT16 : (curIndex+1)*SIZEOF_INT32]))
-			}
+// Step advances the game by one fixed step of game.StepTime seconds with the player holding the
+// direction input. Given the same seed, map and inputs the game always plays out the same way.
+func (curMap *Map) Step(input [2]int) {
+	const deltaTime = game.StepTime
+	if curMap.state.GetPhase() != game.Playing {
+		if curMap.state.Update(deltaTime) {
+			curMap.resetPositions()
 		}
-		newMap.playerObj.SetPos(newMap.GetPlayerSpawn())
-		return &newMap, nil
+		return
 	}
-	return nil, errors.New("Error loading map: given map size and given map data do not match")
-}
 
-func (curMap *Map) Update() {
-	curMap.playerObj.UpdatePlayerPos(curMap.GetSize(), func(pos [2]int) tile.TileType { return curMap.GetMapTile(pos).Type })
-}
+	curMap.playerObj.Step(deltaTime, &curMap.moveMap, input)
 
-func RegisterMapBindings(curMap *Map, tTile *tile.Tile, camera *rendering.Camera) {
-	input.RegisterKeyBinding(glfw.KeyW, "Toggle Up Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			tTile.Type = tile.Wall
-			tTile.Flags ^= tile.Up
-			if tTile.Flags&tile.All == 0 {
-				tTile.Type = tile.Blank
-			}
+	reverse := curMap.modes.Update(deltaTime)
+	curMap.house.Update(deltaTime, curMap.ghosts)
+	curMap.fruit.Update(deltaTime)
+	if playerPos := curMap.playerObj.GetPos(); playerPos != curMap.lastPlayerPos {
+		curMap.lastPlayerPos = playerPos
+		curMap.eatFruit(playerPos)
+		big, cleared := curMap.eatDot(playerPos)
+		if big && curMap.frightenGhosts() {
+			reverse = true
 		}
-	})
-	input.RegisterKeyBinding(glfw.KeyS, "Toggle Down Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			tTile.Type = tile.Wall
-			tTile.Flags ^= tile.Down
-			if tTile.Flags&tile.All == 0 {
-				tTile.Type = tile.Blank
-			}
+		if cleared {
+			curMap.nextLevel()
+			return
 		}
-	})
-	input.RegisterKeyBinding(glfw.KeyA, "Toggle Left Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			tTile.Type = tile.Wall
-			tTile.Flags ^= tile.Left
-			if tTile.Flags&tile.All == 0 {
-				tTile.Type = tile.Blank
-			}
+	}
+	if reverse {
+		for i := range curMap.ghosts {
+			curMap.ghosts[i].Reverse()
 		}
-	})
-	input.RegisterKeyBinding(glfw.KeyD, "Toggle Right Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			tTile.Type = tile.Wall
-			tTile.Flags ^= tile.Right
-			if tTile.Flags&tile.All == 0 {
-				tTile.Type = tile.Blank
-			}
+	}
+
+	info := ghost.ChaseInfo{PlayerPos: curMap.playerObj.GetPos(), PlayerDir: curMap.playerObj.GetDir()}
+	for _, curGhost := range curMap.ghosts {
+		if curGhost.GetPersonality() == ghost.Blinky {
+			info.BlinkyPos = curGhost.GetPos()
 		}
-	})
-	input.RegisterKeyBinding(glfw.KeyR, "Toggle Auto Wall Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			if tTile.Type != tile.Wall {
-				tTile.Type = tile.Wall
-			} else {
-				if tTile.Flags&tile.All != 0 {
-					tTile.Flags &= 0xFFF0
-				} else {
-					tTile.Type = tile.Blank
-				}
-			}
+	}
+	level := game.GetLevel(curMap.state.Level)
+	for i := range curMap.ghosts {
+		mode := curMap.ghostMode(&curMap.ghosts[i])
+		if curMap.getTileType(curMap.ghosts[i].GetPos()) == tile.Tunnel {
+			curMap.ghosts[i].SetSpeed(level.GhostTunnelSpeed)
+		} else if mode == ghost.Frightened {
+			curMap.ghosts[i].SetSpeed(level.FrightenedGhostSpeed)
+		} else {
+			curMap.ghosts[i].SetSpeed(level.GhostSpeed)
 		}
-	})
-	input.RegisterKeyBinding(glfw.KeyE, "Toggle Dot Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			if tTile.Type != tile.Dot {
-				tTile.Type = tile.Dot
-			} else {
-				tTile.Type = tile.Blank
-			}
+		curMap.ghosts[i].Step(deltaTime, &curMap.moveMap, info, mode, curMap.rng)
+	}
+	curMap.checkGhostCollisions()
+}
+
+// ghostMode returns the mode a ghost follows. A ghost eaten during a frightened phase goes back to the current scatter or chase phase.
+func (curMap *Map) ghostMode(curGhost *ghost.Ghost) ghost.Mode {
+	if curMap.modes.GetMode() == ghost.Frightened && curGhost.IsFrightened() {
+		return ghost.Frightened
+	}
+	return curMap.modes.GetPhaseMode()
+}
 
+// frightenGhosts starts a frightened phase and returns true if the ghosts have to reverse
+func (curMap *Map) frightenGhosts() bool {
+	curMap.state.ResetGhostCombo()
+	for i := range curMap.ghosts {
+		curMap.ghosts[i].SetFrightened(true)
+	}
+	return curMap.modes.Frighten()
+}
+
+// checkGhostCollisions kills the player when it touches a ghost or eats the ghost if it is frightened.
+// A collision is either both on the same tile or the two swapping tiles with each other.
+func (curMap *Map) checkGhostCollisions() {
+	playerPos := curMap.playerObj.GetPos()
+	playerTarget := curMap.playerObj.GetTargetPos()
+	for i := range curMap.ghosts {
+		curGhost := &curMap.ghosts[i]
+		ghostPos := curGhost.GetPos()
+		if ghostPos != playerPos && (curGhost.GetTargetPos() != playerPos || playerTarget != ghostPos) {
+			continue
 		}
-	})
-	input.RegisterKeyBinding(glfw.KeyQ, "Toggle Big Dot Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			if tTile.Type != tile.DotBig {
-				tTile.Type = tile.DotBig
-			} else {
-				tTile.Type = tile.Blank
-			}
+		if curMap.ghostMode(curGhost) == ghost.Frightened {
+			curMap.state.EatGhost(ghostPos)
+			curGhost.ResetPos()
+			curGhost.Release()
+			continue
 		}
-	})
-	input.RegisterKeyBinding(glfw.KeyT, "Toggle Player Spawn Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			if tTile.Type != tile.PlayerSpawn {
-				tTile.Type = tile.PlayerSpawn
-			} else {
-				tTile.Type = tile.Blank
+		curMap.state.Kill(playerPos)
+		return
+	}
+}
+
+// applyLevel sets the speeds and ghost mode timings for the current level
+func (curMap *Map) applyLevel() {
+	level := game.GetLevel(curMap.state.Level)
+	curMap.modes = ghost.NewModeScheduler(level.Phases, level.FrightenedTime)
+	curMap.house = ghost.NewHouse(level.GhostDotLimits, level.GhostReleaseTime)
+	curMap.fruit = game.NewFruitSpawner(level)
+	curMap.playerObj.SetSpeed(level.PlayerSpeed)
+	for i := range curMap.ghosts {
+		curMap.ghosts[i].SetSpeed(level.GhostSpeed)
+	}
+}
+
+// Load loads a text or JSON map depending on the extension of filename and a binary map otherwise
+func Load(filename string) (*Map, error) {
+	if strings.HasSuffix(filename, TextExtension) {
+		return LoadMapFromText(filename)
+	}
+	if strings.HasSuffix(filename, JSONExtension) {
+		return LoadMapFromJSON(filename)
+	}
+	return LoadMapFromFile(filename)
+}
+
+// Save saves a text or JSON map depending on the extension of filename and a binary map otherwise
+func (curMap *Map) Save(filename string) error {
+	if strings.HasSuffix(filename, TextExtension) {
+		return curMap.SaveToText(filename)
+	}
+	if strings.HasSuffix(filename, JSONExtension) {
+		return curMap.SaveToJSON(filename)
+	}
+	return curMap.SaveToFile(filename)
+}
+
+// GetFilename returns the file the map was loaded from or saved to
+func (curMap *Map) GetFilename() string {
+	return curMap.filename
+}
+
+// snapshotDots records the dots on the map so nextLevel can put them back on maps without a file
+func (curMap *Map) snapshotDots() {
+	curMap.levelDots = make([][]tile.TileType, len(curMap.layers[Pickups]))
+	for i, col := range curMap.layers[Pickups] {
+		curMap.levelDots[i] = make([]tile.TileType, len(col))
+		for j, curTile := range col {
+			if isDot(curTile.Type) {
+				curMap.levelDots[i][j] = curTile.Type
 			}
 		}
-	})
-	input.RegisterKeyBinding(glfw.KeyZ, "Clear Tile", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			tTile.Type = tile.Blank
-			tTile.Flags = 0x0
-		}
-	})
-	input.RegisterKeyBinding(glfw.KeyX, "Toggle WireFrame", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			rendering.RenderWireframe ^= 1
-		}
-
-	})
-	input.RegisterKeyBinding(glfw.KeyC, "Load Map", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			filename, err := dialog.File().Filter("*.tmap", "tmap").Load()
-			if err != nil {
-				fmt.Println("Error getting map filename:", err)
-				return
-			}
-			newMap, err := LoadMapFromFile(filename)
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-			*curMap = *newMap
-		}
-	})
-	input.RegisterKeyBinding(glfw.KeyV, "Save Map", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			filename, err := dialog.File().Filter("*.tmap", "tmap").Title("Save Map").Save()
-			if err != nil {
-				fmt.Println("Error getting map filename:", err)
-				return
-			}
-			err = curMap.SaveToFile(filename)
-			if err != nil {
-				fmt.Println(err)
+	}
+}
+
+// nextLevel puts back the dots of the map file and starts the next level. Maps that have never
+// been saved or whose file can no longer be loaded at their size get back the dots recorded by
+// snapshotDots instead.
+func (curMap *Map) nextLevel() {
+	if !curMap.reloadDots() {
+		for i, col := range curMap.levelDots {
+			for j, dotType := range col {
+				if isDot(dotType) {
+					curMap.layers[Pickups][i][j].Type = dotType
+				}
 			}
 		}
-	})
-	input.RegisterKeyBinding(glfw.KeyF, "Load Test Map", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Release {
-			newMap, err := LoadMapFromFile("assets/maps/smallTestMap.tmap")
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-			*curMap = *newMap
-		}
-	})
-	input.RegisterMouseButtonBinding("map editor click", func(w *glfw.Window, button glfw.MouseButton, mod glfw.ModifierKey) {
-		mouseX, mouseY := w.GetCursorPos()
-		matProjection := camera.ProjectionMatrix.Mul4(*camera.ViewMatrix).Inv()
-		worldPointf := rendering.ScreenToWorldSpace(w, [2]float64{mouseX, mouseY}, matProjection)
-		worldPoint := []int{int(math.Floor(float64(worldPointf[0] + 0.5))), int(math.Floor(float64(worldPointf[2] + 0.5)))}
-		if worldPoint[0] >= 0 && worldPoint[1] >= 0 && worldPoint[0] < int(curMap.size[0]) && worldPoint[1] < int(curMap.size[1]) {
-			cTile := &curMap.tMap[worldPoint[0]][worldPoint[1]]
-			tileChange := *tTile
-			if button == glfw.MouseButton2 {
-				tileChange.Type = tile.Blank
-				tileChange.Flags = 0x0
+	}
+	curMap.state.NextLevel(curMap.countDots())
+	curMap.applyLevel()
+	curMap.resetPositions()
+}
+
+// reloadDots replaces the dots on the map with the ones in the file the map was loaded from or
+// saved to and returns false if there is no file or it can not be loaded at the size of the map
+func (curMap *Map) reloadDots() bool {
+	if curMap.filename == "" {
+		return false
+	}
+	fileMap, err := Load(curMap.filename)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	if fileMap.GetSize() != curMap.GetSize() {
+		return false
+	}
+	for i, col := range curMap.layers[Pickups] {
+		for j := range col {
+			if fileType := fileMap.layers[Pickups][i][j].Type; isDot(fileType) {
+				col[j].Type = fileType
+			} else if isDot(col[j].Type) {
+				col[j].Type = tile.Blank
 			}
-			curMap.ChangeMapTile(cTile, tileChange.Type, tileChange.Flags)
 		}
-	})
+	}
+	return true
+}
+
+// resetPositions puts the player back on its spawn and every ghost back in its home
+func (curMap *Map) resetPositions() {
+	spawn := curMap.GetPlayerSpawn()
+	curMap.fruit.Despawn()
+	curMap.playerObj.SetPos(spawn)
+	curMap.lastPlayerPos = spawn
+	for i := range curMap.ghosts {
+		curMap.ghosts[i].ResetPos()
+	}
+}
+
+// eatDot consumes the dot at pos if there is one and returns whether it was a big dot and whether
+// it was the last dot of the level
+func (curMap *Map) eatDot(pos [2]int) (bool, bool) {
+	cTile := &curMap.layers[Pickups][pos[0]][pos[1]]
+	if !isDot(cTile.Type) {
+		return false, false
+	}
+	big := cTile.Type == tile.DotBig
+	cleared := curMap.state.EatDot(pos, big)
+	curMap.house.DotEaten(curMap.ghosts)
+	cTile.Type = tile.Blank
+	if curMap.fruit.DotEaten() {
+		if fruitPos, ok := curMap.GetFruitSpawn(); ok {
+			curMap.state.FruitSpawned(fruitPos)
+		} else {
+			curMap.fruit.Despawn()
+		}
+	}
+	return big, cleared
+}
+
+// eatFruit collects the bonus fruit if there is one at pos
+func (curMap *Map) eatFruit(pos [2]int) {
+	if fruitPos, ok := curMap.GetFruitSpawn(); ok && fruitPos == pos && curMap.fruit.IsActive() {
+		_, points := curMap.fruit.Eat()
+		curMap.state.EatFruit(pos, points)
+	}
 }
@@ -0,0 +1,129 @@
+package maps
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sunkink29/3dpacman/game"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// stepRecord is what a game looks like after a step
+type stepRecord struct {
+	PlayerPos    [2]int
+	PlayerTarget [2]int
+	Ghosts       [][2][2]int
+	Score        int
+	Lives        int
+	Level        int
+	DotsLeft     int
+	Events       []game.Event
+}
+
+// playGame steps the map in filename steps times with seed, holding each direction for a while in turn
+func playGame(t *testing.T, filename string, seed int64, steps int) []stepRecord {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	curMap, err := LoadMap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	curMap.SetSeed(seed)
+	dirs := [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}, {0, 0}}
+	records := make([]stepRecord, steps)
+	for i := range records {
+		curMap.Step(dirs[(i/97)%len(dirs)])
+		state := curMap.GetState()
+		record := stepRecord{
+			PlayerPos:    curMap.GetPlayer().GetPos(),
+			PlayerTarget: curMap.GetPlayer().GetTargetPos(),
+			Score:        state.Score,
+			Lives:        state.Lives,
+			Level:        state.Level,
+			DotsLeft:     state.DotsLeft,
+			Events:       state.PollEvents(),
+		}
+		ghosts := curMap.GetGhosts()
+		for j := range ghosts {
+			record.Ghosts = append(record.Ghosts, [2][2]int{ghosts[j].GetPos(), ghosts[j].GetTargetPos()})
+		}
+		records[i] = record
+	}
+	return records
+}
+
+func TestStepDeterministic(t *testing.T) {
+	const steps = 20 * game.TickRate * 60
+	for _, filename := range []string{"../assets/maps/bigTestMap.tmap", "../assets/maps/smallTestMap.tmap"} {
+		first := playGame(t, filename, 7, steps)
+		second := playGame(t, filename, 7, steps)
+		for i := range first {
+			if !reflect.DeepEqual(first[i], second[i]) {
+				t.Fatalf("%s: step %d differs between runs: %+v and %+v", filename, i, first[i], second[i])
+			}
+		}
+		if last := first[len(first)-1]; last.Score == 0 {
+			t.Errorf("%s: the player scored nothing so the runs show little", filename)
+		}
+	}
+}
+
+// pickupTypes returns the types of the tiles on the pickups layer of the top row of curMap
+func pickupTypes(curMap *Map) []tile.TileType {
+	var types []tile.TileType
+	for x := 0; x < curMap.GetSize()[0]; x++ {
+		types = append(types, curMap.GetLayerTile(Pickups, [2]int{x, 0}).Type)
+	}
+	return types
+}
+
+func TestNextLevelDots(t *testing.T) {
+	fileMap := CreateEmptyMap([2]int{4, 1})
+	fileMap.ChangeMapTile([2]int{0, 0}, tile.Dot, 0)
+	fileMap.ChangeMapTile([2]int{1, 0}, tile.DotBig, 0)
+	filename := filepath.Join(t.TempDir(), "dots.tmap")
+	if err := fileMap.SaveToFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	curMap, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// dots eaten or placed since loading are replaced by the ones in the file
+	curMap.PlaceTile(Pickups, [2]int{0, 0}, tile.Blank, 0)
+	curMap.PlaceTile(Pickups, [2]int{1, 0}, tile.Blank, 0)
+	curMap.PlaceTile(Pickups, [2]int{3, 0}, tile.Dot, 0)
+	curMap.nextLevel()
+	if got, want := pickupTypes(curMap), []tile.TileType{tile.Dot, tile.DotBig, tile.Blank, tile.Blank}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dots are %v, want %v from the file", got, want)
+	}
+	if state := curMap.GetState(); state.Level != 2 || state.DotsLeft != 2 {
+		t.Errorf("level is %d with %d dots, want 2 with 2", state.Level, state.DotsLeft)
+	}
+
+	// the file is read again at every level so changes saved to it are used
+	fileMap.ChangeMapTile([2]int{0, 0}, tile.Blank, 0)
+	fileMap.ChangeMapTile([2]int{2, 0}, tile.Dot, 0)
+	if err := fileMap.SaveToFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	curMap.nextLevel()
+	if got, want := pickupTypes(curMap), []tile.TileType{tile.Blank, tile.DotBig, tile.Dot, tile.Blank}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dots are %v, want %v from the changed file", got, want)
+	}
+
+	// maps that were never saved get back the dots they started with
+	newMap := CreateEmptyMap([2]int{4, 1})
+	newMap.ChangeMapTile([2]int{3, 0}, tile.Dot, 0)
+	newMap.Restart()
+	newMap.eatDot([2]int{3, 0})
+	newMap.nextLevel()
+	if got, want := pickupTypes(&newMap), []tile.TileType{tile.Blank, tile.Blank, tile.Blank, tile.Dot}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dots are %v, want %v from the start of the level", got, want)
+	}
+}
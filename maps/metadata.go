@@ -0,0 +1,127 @@
+package maps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Metadata describes a map for level select screens and map tools. It does not change how the map plays.
+type Metadata struct {
+	Title       string
+	Author      string
+	Description string
+	// Created is set the first time the map is saved and Modified every time it is saved
+	Created  time.Time
+	Modified time.Time
+	// Levels are the levels the map is recommended for, counting from 1
+	Levels []int
+	// ParScore is the score a good player reaches on the map, 0 if it has none
+	ParScore int
+	// Thumbnail is a PNG image of the map, empty if it has none
+	Thumbnail []byte
+}
+
+// MaxThumbnailSize is the largest thumbnail in bytes a map may hold
+const MaxThumbnailSize = 1 << 20
+
+// GetMetadata returns a copy of the metadata of the map
+func (curMap *Map) GetMetadata() Metadata {
+	meta := curMap.metadata
+	meta.Levels = append([]int(nil), meta.Levels...)
+	meta.Thumbnail = append([]byte(nil), meta.Thumbnail...)
+	return meta
+}
+
+// SetMetadata replaces the metadata of the map. The timestamps are kept as given so they are
+// normally left as returned by GetMetadata.
+func (curMap *Map) SetMetadata(meta Metadata) error {
+	if err := meta.check(); err != nil {
+		return err
+	}
+	meta.Levels = append([]int(nil), meta.Levels...)
+	meta.Thumbnail = append([]byte(nil), meta.Thumbnail...)
+	curMap.metadata = meta
+	return nil
+}
+
+// SetThumbnail replaces the thumbnail of the map with img encoded as a PNG
+func (curMap *Map) SetThumbnail(img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return errors.New(fmt.Sprint("Error encoding thumbnail: ", err))
+	}
+	if buf.Len() > MaxThumbnailSize {
+		return errors.New(fmt.Sprint("Error encoding thumbnail: thumbnail is ", buf.Len(), " bytes, the most is ", MaxThumbnailSize))
+	}
+	curMap.metadata.Thumbnail = buf.Bytes()
+	return nil
+}
+
+// GetThumbnail returns the thumbnail of the map or nil if it has none
+func (curMap *Map) GetThumbnail() (image.Image, error) {
+	if len(curMap.metadata.Thumbnail) == 0 {
+		return nil, nil
+	}
+	img, err := png.Decode(bytes.NewReader(curMap.metadata.Thumbnail))
+	if err != nil {
+		return nil, errors.New(fmt.Sprint("Error decoding thumbnail: ", err))
+	}
+	return img, nil
+}
+
+// GetTitle returns the title of the map or the name of its file if it has no title
+func (curMap *Map) GetTitle() string {
+	if curMap.metadata.Title != "" || curMap.filename == "" {
+		return curMap.metadata.Title
+	}
+	name := filepath.Base(curMap.filename)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// IsEmpty returns true if none of the metadata is set
+func (meta *Metadata) IsEmpty() bool {
+	return meta.Title == "" && meta.Author == "" && meta.Description == "" && meta.Created.IsZero() &&
+		meta.Modified.IsZero() && len(meta.Levels) == 0 && meta.ParScore == 0 && len(meta.Thumbnail) == 0
+}
+
+// touchMetadata updates the timestamps when the map is saved
+func (curMap *Map) touchMetadata() {
+	now := time.Now().UTC().Truncate(time.Second)
+	if curMap.metadata.Created.IsZero() {
+		curMap.metadata.Created = now
+	}
+	curMap.metadata.Modified = now
+}
+
+// check returns an error if the metadata can not be saved
+func (meta *Metadata) check() error {
+	for _, level := range meta.Levels {
+		if level < 1 || level > maxMetadataLevel {
+			return errors.New(fmt.Sprint("Error setting metadata: bad level ", level))
+		}
+	}
+	if meta.ParScore < 0 || int64(meta.ParScore) > maxParScore {
+		return errors.New(fmt.Sprint("Error setting metadata: bad par score ", meta.ParScore))
+	}
+	if len(meta.Thumbnail) > MaxThumbnailSize {
+		return errors.New(fmt.Sprint("Error setting metadata: thumbnail is ", len(meta.Thumbnail), " bytes, the most is ", MaxThumbnailSize))
+	}
+	if len(meta.Thumbnail) > 0 {
+		if _, err := png.DecodeConfig(bytes.NewReader(meta.Thumbnail)); err != nil {
+			return errors.New(fmt.Sprint("Error setting metadata: thumbnail is not a PNG: ", err))
+		}
+	}
+	return nil
+}
+
+// maxMetadataLevel is the highest level that fits in the map file
+const maxMetadataLevel = 1<<16 - 1
+
+// maxParScore is the highest par score that fits in the map file
+const maxParScore = 1<<32 - 1
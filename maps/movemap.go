@@ -0,0 +1,71 @@
+package maps
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+)
+
+// A .pmap file holds the movement map of the .tmap file with the same name so it does not have to
+// be worked out when the map is loaded. It starts with the magic "PMAP", a uint16 version, the
+// width and height as uint32 and the CRC-32 of the layers it was made from as uint32. Then comes a
+// byte for every tile, column by column, laid out as described by movement.MoveMap.
+// All numbers are little endian.
+const (
+	moveMapMagic      = "PMAP"
+	moveMapVersion    = 1
+	moveMapHeaderSize = len(moveMapMagic) + sizeofInt16 + sizeofInt32*3
+)
+
+// moveMapFilename returns the name of the .pmap file that goes with a .tmap file
+func moveMapFilename(filename string) string {
+	return strings.TrimSuffix(filename, ".tmap") + ".pmap"
+}
+
+// tileChecksum returns the CRC-32 of the floor and walls layers, the tiles the movement map is
+// made from, so a movement map made from other tiles is not used
+func (curMap *Map) tileChecksum() uint32 {
+	checksum := crc32.ChecksumIEEE(curMap.encodeLayer(Floor))
+	return crc32.Update(checksum, crc32.IEEETable, curMap.encodeLayer(Walls))
+}
+
+func (curMap *Map) encodeMoveMap() []byte {
+	size := curMap.GetSize()
+	data := make([]byte, 0, moveMapHeaderSize+size[0]*size[1])
+	data = append(data, moveMapMagic...)
+	data = appendUint16(data, moveMapVersion)
+	data = appendUint32(data, uint32(size[0]))
+	data = appendUint32(data, uint32(size[1]))
+	data = appendUint32(data, curMap.tileChecksum())
+	for i := 0; i < size[0]; i++ {
+		for j := 0; j < size[1]; j++ {
+			data = append(data, curMap.moveMap.GetMask([2]int{i, j}))
+		}
+	}
+	return data
+}
+
+// decodeMoveMap replaces the movement map with the one in data. It returns false and leaves the
+// movement map as it was if data is not a movement map for the tiles on the map.
+func (curMap *Map) decodeMoveMap(data []byte) bool {
+	if len(data) < moveMapHeaderSize || string(data[:len(moveMapMagic)]) != moveMapMagic {
+		return false
+	}
+	data = data[len(moveMapMagic):]
+	version := binary.LittleEndian.Uint16(data)
+	width := binary.LittleEndian.Uint32(data[sizeofInt16:])
+	height := binary.LittleEndian.Uint32(data[sizeofInt16+sizeofInt32:])
+	checksum := binary.LittleEndian.Uint32(data[sizeofInt16+sizeofInt32*2:])
+	data = data[sizeofInt16+sizeofInt32*3:]
+	size := curMap.GetSize()
+	if version != moveMapVersion || int64(width) != int64(size[0]) || int64(height) != int64(size[1]) ||
+		len(data) != size[0]*size[1] || checksum != curMap.tileChecksum() {
+		return false
+	}
+	for i := 0; i < size[0]; i++ {
+		for j := 0; j < size[1]; j++ {
+			curMap.moveMap.SetMask([2]int{i, j}, data[i*size[1]+j])
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// Anchor is the side or corner of a map that stays in place when it is resized, the top of a map
+// being the row with y 0
+type Anchor int
+
+const (
+	TopLeft Anchor = iota
+	Top
+	TopRight
+	Left
+	Center
+	Right
+	BottomLeft
+	Bottom
+	BottomRight
+)
+
+var anchorNames = [...]string{"top left", "top", "top right", "left", "centre", "right", "bottom left", "bottom", "bottom right"}
+
+func (anchor Anchor) String() string {
+	if anchor < 0 || int(anchor) >= len(anchorNames) {
+		return fmt.Sprint("Anchor(", int(anchor), ")")
+	}
+	return anchorNames[anchor]
+}
+
+// offset returns how far the tiles of a map move when it is resized from oldSize to newSize
+func (anchor Anchor) offset(oldSize [2]int, newSize [2]int) [2]int {
+	// the column and row of the anchor, 0 to 2 from the top left
+	col, row := int(anchor)%3, int(anchor)/3
+	return [2]int{(newSize[0] - oldSize[0]) * col / 2, (newSize[1] - oldSize[1]) * row / 2}
+}
+
+// Resize changes the size of the map keeping the side or corner given by anchor in place. Tiles
+// moved off the map and ghost spawns on them are dropped and the new tiles are blank. Walls are
+// disconnected from the tiles that were cut off or added so none lead off the map.
+// The game starts over on the resized map.
+func (curMap *Map) Resize(size [2]int, anchor Anchor) error {
+	if size[0] < 1 || size[1] < 1 || size[0] > MaxMapSize || size[1] > MaxMapSize {
+		return errors.New(fmt.Sprint("Error resizing map: bad map size ", size[0], "x", size[1]))
+	}
+	if anchor < TopLeft || anchor > BottomRight {
+		return errors.New(fmt.Sprint("Error resizing map: unknown anchor ", int(anchor)))
+	}
+	offset := anchor.offset(curMap.GetSize(), size)
+	onMap := func(pos [2]int) bool {
+		return pos[0] >= 0 && pos[1] >= 0 && pos[0] < size[0] && pos[1] < size[1]
+	}
+	for layer, old := range curMap.layers {
+		curMap.layers[layer] = newLayer(size)
+		for i, col := range old {
+			for j, cTile := range col {
+				pos := [2]int{i + offset[0], j + offset[1]}
+				if onMap(pos) {
+					newTile := &curMap.layers[layer][pos[0]][pos[1]]
+					newTile.Type = cTile.Type
+					newTile.Flags = cTile.Flags
+				}
+			}
+		}
+	}
+	curMap.size = [2]int32{int32(size[0]), int32(size[1])}
+	for personality, pos := range curMap.ghostSpawns {
+		pos = [2]int{pos[0] + offset[0], pos[1] + offset[1]}
+		if onMap(pos) {
+			curMap.ghostSpawns[personality] = pos
+		} else {
+			delete(curMap.ghostSpawns, personality)
+		}
+	}
+
+	// only walls next to the cut or added tiles can lead off the map or to a tile that is not a wall
+	disconnectWalls(&curMap.layers, size)
+	curMap.rebuildMoveMap()
+	curMap.Restart()
+	return nil
+}
+
+// disconnectWalls clears the directions of the walls in layers of the given size that lead off the
+// layers or to a tile that is not a wall
+func disconnectWalls(layers *[NumLayers][][]tile.Tile, size [2]int) {
+	for layer := range layers {
+		for i, col := range layers[layer] {
+			for j := range col {
+				if col[j].Type != tile.Wall {
+					continue
+				}
+				for _, side := range wallSides {
+					next := [2]int{i + side.dir[0], j + side.dir[1]}
+					onLayer := next[0] >= 0 && next[1] >= 0 && next[0] < size[0] && next[1] < size[1]
+					if col[j].Flags&side.flag != 0 && (!onLayer || layers[layer][next[0]][next[1]].Type != tile.Wall) {
+						col[j].Flags &^= side.flag
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// StampDir is the directory the stamp library is kept in
+const StampDir = "assets/stamps"
+
+// Stamp is a rectangle of tiles on every layer, such as a ghost house or a tunnel, that can be
+// copied from a map and placed on one again. Its walls only lead to other walls in the stamp.
+type Stamp struct {
+	size   [2]int
+	layers [NumLayers][][]tile.Tile
+}
+
+// NewStamp returns a stamp of blank tiles
+func NewStamp(size [2]int) *Stamp {
+	stamp := &Stamp{size: size}
+	for layer := range stamp.layers {
+		stamp.layers[layer] = newLayer(size)
+	}
+	return stamp
+}
+
+// CopyStamp returns the tiles in the rectangle of the map with corners minPos and maxPos as a stamp
+func (curMap *Map) CopyStamp(minPos [2]int, maxPos [2]int) *Stamp {
+	stamp := NewStamp([2]int{maxPos[0] - minPos[0] + 1, maxPos[1] - minPos[1] + 1})
+	for layer := range stamp.layers {
+		for i, col := range stamp.layers[layer] {
+			for j := range col {
+				cTile := curMap.layers[layer][minPos[0]+i][minPos[1]+j]
+				col[j].Type = cTile.Type
+				col[j].Flags = cTile.Flags
+			}
+		}
+	}
+	disconnectWalls(&stamp.layers, stamp.size)
+	return stamp
+}
+
+func (stamp *Stamp) GetSize() [2]int {
+	return stamp.size
+}
+
+func (stamp *Stamp) GetLayerTile(layer Layer, pos [2]int) tile.Tile {
+	return stamp.layers[layer][pos[0]][pos[1]]
+}
+
+// transform returns a stamp of the given size with each tile moved to where move puts it and its
+// flags changed by turn
+func (stamp *Stamp) transform(size [2]int, move func(pos [2]int) [2]int, turn func(tile.TileFlag) tile.TileFlag) *Stamp {
+	newStamp := NewStamp(size)
+	for layer := range stamp.layers {
+		for i, col := range stamp.layers[layer] {
+			for j, cTile := range col {
+				pos := move([2]int{i, j})
+				newTile := &newStamp.layers[layer][pos[0]][pos[1]]
+				newTile.Type = cTile.Type
+				newTile.Flags = turn(cTile.Flags)
+			}
+		}
+	}
+	return newStamp
+}
+
+// RotateClockwise returns the stamp turned a quarter turn clockwise
+func (stamp *Stamp) RotateClockwise() *Stamp {
+	return stamp.transform([2]int{stamp.size[1], stamp.size[0]}, func(pos [2]int) [2]int {
+		return [2]int{stamp.size[1] - 1 - pos[1], pos[0]}
+	}, tile.TileFlag.RotateClockwise)
+}
+
+// FlipLeftRight returns the stamp mirrored from left to right
+func (stamp *Stamp) FlipLeftRight() *Stamp {
+	return stamp.transform(stamp.size, func(pos [2]int) [2]int {
+		return [2]int{stamp.size[0] - 1 - pos[0], pos[1]}
+	}, tile.TileFlag.FlipLeftRight)
+}
+
+// FlipUpDown returns the stamp mirrored from top to bottom
+func (stamp *Stamp) FlipUpDown() *Stamp {
+	return stamp.transform(stamp.size, func(pos [2]int) [2]int {
+		return [2]int{pos[0], stamp.size[1] - 1 - pos[1]}
+	}, tile.TileFlag.FlipUpDown)
+}
+
+// PlaceStamp replaces the tiles on every layer with the stamp, its top left corner at pos. Tiles
+// of the stamp that would be off the map are left out. Walls next to the stamp that led into it
+// stay connected if there is a wall there now and are disconnected otherwise.
+func (curMap *Map) PlaceStamp(stamp *Stamp, pos [2]int) {
+	size := curMap.GetSize()
+	onMap := func(pos [2]int) bool {
+		return pos[0] >= 0 && pos[1] >= 0 && pos[0] < size[0] && pos[1] < size[1]
+	}
+	inStamp := func(mapPos [2]int) bool {
+		return mapPos[0] >= pos[0] && mapPos[1] >= pos[1] && mapPos[0] < pos[0]+stamp.size[0] && mapPos[1] < pos[1]+stamp.size[1]
+	}
+	for layer := range stamp.layers {
+		for i, col := range stamp.layers[layer] {
+			for j, cTile := range col {
+				mapPos := [2]int{pos[0] + i, pos[1] + j}
+				if !onMap(mapPos) {
+					continue
+				}
+				flags := cTile.Flags
+				if cTile.Type == tile.Wall {
+					// the walls they led to were left out
+					for _, side := range wallSides {
+						if !onMap([2]int{mapPos[0] + side.dir[0], mapPos[1] + side.dir[1]}) {
+							flags &^= side.flag
+						}
+					}
+				}
+				curMap.PlaceTile(Layer(layer), mapPos, cTile.Type, flags)
+			}
+		}
+	}
+
+	// the walls around the stamp are the ones that can lead into it
+	for layer := range curMap.layers {
+		for x := pos[0] - 1; x <= pos[0]+stamp.size[0]; x++ {
+			for y := pos[1] - 1; y <= pos[1]+stamp.size[1]; y++ {
+				outside := [2]int{x, y}
+				if !onMap(outside) || inStamp(outside) || curMap.layers[layer][x][y].Type != tile.Wall {
+					continue
+				}
+				wall := &curMap.layers[layer][x][y]
+				for _, side := range wallSides {
+					next := [2]int{x + side.dir[0], y + side.dir[1]}
+					if wall.Flags&side.flag == 0 || !onMap(next) || !inStamp(next) {
+						continue
+					}
+					if nextTile := &curMap.layers[layer][next[0]][next[1]]; nextTile.Type == tile.Wall {
+						nextTile.Flags |= side.opposite
+					} else {
+						wall.Flags &^= side.flag
+					}
+				}
+			}
+		}
+	}
+}
+
+// LoadStamp loads a map file of any format as a stamp of the whole map
+func LoadStamp(filename string) (*Stamp, error) {
+	curMap, err := Load(filename)
+	if err != nil {
+		return nil, err
+	}
+	size := curMap.GetSize()
+	return curMap.CopyStamp([2]int{0, 0}, [2]int{size[0] - 1, size[1] - 1}), nil
+}
+
+// Save saves the stamp as a map the size of the stamp, a JSON map if filename has no extension
+// as it keeps the direction of every wall. A .tmap stamp is saved without a movement map so only
+// stamps end up in the stamp library.
+func (stamp *Stamp) Save(filename string) error {
+	if filepath.Ext(filename) == "" {
+		filename += JSONExtension
+	}
+	curMap := CreateEmptyMap(stamp.size)
+	for layer := range stamp.layers {
+		for i, col := range stamp.layers[layer] {
+			for j, cTile := range col {
+				if !curMap.PlaceTile(Layer(layer), [2]int{i, j}, cTile.Type, cTile.Flags) {
+					return errors.New(fmt.Sprint("Error saving stamp: ", cTile.Type, " can not be on the ", Layer(layer), " layer"))
+				}
+			}
+		}
+	}
+	meta := curMap.GetMetadata()
+	meta.Title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if err := curMap.SetMetadata(meta); err != nil {
+		return err
+	}
+	if filepath.Ext(filename) == ".tmap" {
+		return curMap.writeFile(filename)
+	}
+	return curMap.Save(filename)
+}
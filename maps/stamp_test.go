@@ -0,0 +1,58 @@
+package maps
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+func TestStampSave(t *testing.T) {
+	curMap := CreateEmptyMap([2]int{4, 3})
+	for x := 0; x < 4; x++ {
+		curMap.ChangeMapTile([2]int{x, 0}, tile.Wall, 0)
+	}
+	curMap.ChangeMapTile([2]int{1, 1}, tile.GhostDoor, 0)
+	curMap.ChangeMapTile([2]int{1, 2}, tile.GhostHouse, 0)
+	curMap.ChangeMapTile([2]int{3, 2}, tile.DotBig, 0)
+	stamp := curMap.CopyStamp([2]int{0, 0}, [2]int{3, 2})
+
+	for _, name := range []string{"stamp.tmap", "stamp" + TextExtension, "stamp" + JSONExtension} {
+		dir := t.TempDir()
+		filename := filepath.Join(dir, name)
+		if err := stamp.Save(filename); err != nil {
+			t.Fatal(err)
+		}
+		// the stamp library should only hold stamps
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			var names []string
+			for _, file := range files {
+				names = append(names, file.Name())
+			}
+			t.Errorf("%s: saving wrote %v, want only the stamp", name, names)
+		}
+
+		newStamp, err := LoadStamp(filename)
+		if err != nil {
+			t.Fatal(name, ": ", err)
+		}
+		if newStamp.GetSize() != stamp.GetSize() {
+			t.Fatalf("%s: size is %v, want %v", name, newStamp.GetSize(), stamp.GetSize())
+		}
+		for layer := Layer(0); layer < NumLayers; layer++ {
+			for x := 0; x < 4; x++ {
+				for y := 0; y < 3; y++ {
+					pos := [2]int{x, y}
+					if got, want := newStamp.GetLayerTile(layer, pos), stamp.GetLayerTile(layer, pos); got.Type != want.Type || got.Flags != want.Flags {
+						t.Errorf("%s: %v tile at %v is %v %v, want %v %v", name, layer, pos, got.Type, got.Flags, want.Type, want.Flags)
+					}
+				}
+			}
+		}
+	}
+}
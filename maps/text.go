@@ -0,0 +1,332 @@
+package maps
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunkink29/3dpacman/ghost"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// TextExtension is the file extension of text maps
+const TextExtension = ".amap"
+
+// A text map starts with a header of "key: value" lines ended by a line holding only textSeparator,
+// followed by one line per row of the map with one character per tile. Text header values holding
+// a line break or starting with a quote are saved as quoted Go strings.
+// Wall directions are not stored, they are worked out from the neighbouring walls when the map is
+// loaded the same way the editor connects an auto wall. Rows shorter than the map are padded with
+// blank tiles so trailing spaces can be left out.
+// Each tile of the map is the one the game sees when the layers are stacked and is put on the layer
+// its type belongs to. Layers that can not be worked out that way follow the map in sections that
+// start with textSeparator and the name of the layer, such as "--- decoration", and replace the
+// whole layer. Each ghost spawn is a "ghost: name x y" line in the header.
+const (
+	textHeader    = "3dpacman map"
+	textSeparator = "---"
+	textVersion   = 1
+)
+
+var tileChars = map[tile.TileType]byte{
+	tile.Blank:       ' ',
+	tile.Wall:        '#',
+	tile.Dot:         '.',
+	tile.DotBig:      'o',
+	tile.PlayerSpawn: 'P',
+	tile.Tunnel:      'T',
+	tile.GhostHouse:  'H',
+	tile.GhostDoor:   '-',
+	tile.FruitSpawn:  'F',
+}
+
+// SaveToText saves the map as a text map
+func (curMap *Map) SaveToText(filename string) error {
+	if !strings.HasSuffix(filename, TextExtension) {
+		filename += TextExtension
+	}
+
+	if err := curMap.metadata.check(); err != nil {
+		return err
+	}
+	curMap.touchMetadata()
+	data, err := curMap.encodeText()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		return errors.New(fmt.Sprint("Error Saving Map to file:", err))
+	}
+	curMap.filename = filename
+	return nil
+}
+
+// LoadMapFromText reads a map saved by SaveToText
+func LoadMapFromText(filename string) (*Map, error) {
+	mapBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprint("Error Reading Map:", err))
+	}
+	newMap, err := decodeText(mapBytes)
+	if err != nil {
+		return nil, err
+	}
+	newMap.filename = filename
+	newMap.Restart()
+	return newMap, nil
+}
+
+func (curMap *Map) encodeText() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, textHeader)
+	fmt.Fprintln(&buf, "version:", textVersion)
+	fmt.Fprintln(&buf, "size:", curMap.size[0], curMap.size[1])
+	// write the ghosts in order so saving the same map always gives the same file
+	for personality := ghost.Blinky; personality <= ghost.Clyde; personality++ {
+		if pos, ok := curMap.ghostSpawns[personality]; ok {
+			fmt.Fprintln(&buf, "ghost:", personality, pos[0], pos[1])
+		}
+	}
+	curMap.metadata.encodeText(&buf)
+	fmt.Fprintln(&buf, textSeparator)
+	err := curMap.writeTextRows(&buf, func(pos [2]int) tile.Tile { return curMap.GetMapTile(pos) })
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range curMap.extraLayers(false) {
+		fmt.Fprintln(&buf, textSeparator, layer)
+		err := curMap.writeTextRows(&buf, func(pos [2]int) tile.Tile { return curMap.GetLayerTile(layer, pos) })
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTextRows writes a row of characters for each row of the map
+func (curMap *Map) writeTextRows(buf *bytes.Buffer, getTile func(pos [2]int) tile.Tile) error {
+	for y := 0; y < int(curMap.size[1]); y++ {
+		row := make([]byte, curMap.size[0])
+		for x := range row {
+			tileType := getTile([2]int{x, y}).Type
+			char, ok := tileChars[tileType]
+			if !ok {
+				return errors.New(fmt.Sprint("Error Saving Map: tile type ", tileType, " at ", x, ",", y, " can not be saved in a text map"))
+			}
+			row[x] = char
+		}
+		buf.Write(bytes.TrimRight(row, " "))
+		buf.WriteByte('\n')
+	}
+	return nil
+}
+
+func decodeText(data []byte) (*Map, error) {
+	charTypes := make(map[byte]tile.TileType, len(tileChars))
+	for tileType, char := range tileChars {
+		charTypes[char] = tileType
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// the thumbnail is written on a single line
+	scanner.Buffer(nil, base64.StdEncoding.EncodedLen(MaxThumbnailSize)+len("thumbnail: ")+1)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != textHeader {
+		return nil, errors.New("Error loading map: not a text map")
+	}
+	lineNum := 1
+	mapSize := [2]int{-1, -1}
+	// the ghost spawns are set once the size is known, it may come after them
+	ghostSpawns := make(map[ghost.Personality][2]int)
+	var meta Metadata
+	for {
+		if !scanner.Scan() {
+			return nil, errors.New("Error loading map: missing end of header")
+		}
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == textSeparator {
+			break
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.New(fmt.Sprint("Error loading map: line ", lineNum, " is not a header field"))
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch key {
+		case "version":
+			if version, err := strconv.Atoi(value); err != nil || version > textVersion {
+				return nil, errors.New(fmt.Sprint("Error loading map: unsupported text map version ", value))
+			}
+		case "size":
+			fields := strings.Fields(value)
+			if len(fields) != 2 {
+				return nil, errors.New(fmt.Sprint("Error loading map: bad size on line ", lineNum))
+			}
+			for i, field := range fields {
+				size, err := strconv.Atoi(field)
+				if err != nil || size < 1 || size > MaxMapSize {
+					return nil, errors.New(fmt.Sprint("Error loading map: bad size on line ", lineNum))
+				}
+				mapSize[i] = size
+			}
+		case "ghost":
+			fields := strings.Fields(value)
+			if len(fields) != 3 {
+				return nil, errors.New(fmt.Sprint("Error loading map: bad ghost spawn on line ", lineNum))
+			}
+			personality, ok := ghost.ParsePersonality(fields[0])
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Error loading map: unknown ghost %q on line %d", fields[0], lineNum))
+			}
+			var pos [2]int
+			for i, field := range fields[1:] {
+				coord, err := strconv.Atoi(field)
+				if err != nil {
+					return nil, errors.New(fmt.Sprint("Error loading map: bad ghost spawn on line ", lineNum))
+				}
+				pos[i] = coord
+			}
+			ghostSpawns[personality] = pos
+		default:
+			if err := meta.decodeTextField(key, value); err != nil {
+				return nil, errors.New(fmt.Sprint("Error loading map: bad ", key, " on line ", lineNum, ": ", err))
+			}
+		}
+	}
+	if err := meta.check(); err != nil {
+		return nil, err
+	}
+	if mapSize[0] == -1 {
+		return nil, errors.New("Error loading map: header has no size")
+	}
+	for personality, pos := range ghostSpawns {
+		if pos[0] < 0 || pos[1] < 0 || pos[0] >= mapSize[0] || pos[1] >= mapSize[1] {
+			return nil, errors.New(fmt.Sprint("Error loading map: ", personality, " spawns outside of the map"))
+		}
+	}
+
+	newMap := CreateEmptyMap(mapSize)
+	y := 0
+	// the layer the rows are put on, -1 for the layer their type belongs to
+	layer := Layer(-1)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, textSeparator+" ") {
+			name := strings.TrimSpace(strings.TrimPrefix(line, textSeparator))
+			var ok bool
+			if layer, ok = ParseLayer(name); !ok {
+				return nil, errors.New(fmt.Sprintf("Error loading map: unknown layer %q on line %d", name, lineNum))
+			}
+			for x := 0; x < mapSize[0]; x++ {
+				for y := 0; y < mapSize[1]; y++ {
+					newMap.SetTile(layer, [2]int{x, y}, tile.Blank, 0)
+				}
+			}
+			y = 0
+			continue
+		}
+		if y >= mapSize[1] {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			return nil, errors.New(fmt.Sprint("Error loading map: more rows than the map height on line ", lineNum))
+		}
+		if len(line) > mapSize[0] {
+			return nil, errors.New(fmt.Sprint("Error loading map: line ", lineNum, " is wider than the map"))
+		}
+		for x := 0; x < len(line); x++ {
+			tileType, ok := charTypes[line[x]]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Error loading map: unknown tile %q on line %d", line[x], lineNum))
+			}
+			if layer == -1 {
+				newMap.ChangeMapTile([2]int{x, y}, tileType, 0)
+			} else if !newMap.SetTile(layer, [2]int{x, y}, tileType, 0) {
+				return nil, errors.New(fmt.Sprint("Error loading map: ", tileType, " can not be on the ", layer, " layer on line ", lineNum))
+			}
+		}
+		y++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New(fmt.Sprint("Error loading map:", err))
+	}
+	for personality, pos := range ghostSpawns {
+		newMap.SetGhostSpawn(personality, pos)
+	}
+	newMap.metadata = meta
+	return &newMap, nil
+}
+
+// encodeText writes the header fields for the metadata that is set
+func (meta *Metadata) encodeText(buf *bytes.Buffer) {
+	writeText := func(key string, text string) {
+		if text == "" {
+			return
+		}
+		if strings.ContainsAny(text, "\r\n") || strings.HasPrefix(text, "\"") || strings.TrimSpace(text) != text {
+			text = strconv.Quote(text)
+		}
+		fmt.Fprintln(buf, key+":", text)
+	}
+	writeText("title", meta.Title)
+	writeText("author", meta.Author)
+	writeText("description", meta.Description)
+	if !meta.Created.IsZero() {
+		fmt.Fprintln(buf, "created:", meta.Created.Format(time.RFC3339))
+	}
+	if !meta.Modified.IsZero() {
+		fmt.Fprintln(buf, "modified:", meta.Modified.Format(time.RFC3339))
+	}
+	if len(meta.Levels) > 0 {
+		fmt.Fprintln(buf, "levels:", strings.Trim(fmt.Sprint(meta.Levels), "[]"))
+	}
+	if meta.ParScore != 0 {
+		fmt.Fprintln(buf, "par:", meta.ParScore)
+	}
+	if len(meta.Thumbnail) > 0 {
+		fmt.Fprintln(buf, "thumbnail:", base64.StdEncoding.EncodeToString(meta.Thumbnail))
+	}
+}
+
+// decodeTextField reads a metadata header field, fields it does not know are skipped
+func (meta *Metadata) decodeTextField(key string, value string) error {
+	var err error
+	if strings.HasPrefix(value, "\"") {
+		if value, err = strconv.Unquote(value); err != nil {
+			return err
+		}
+	}
+	switch key {
+	case "title":
+		meta.Title = value
+	case "author":
+		meta.Author = value
+	case "description":
+		meta.Description = value
+	case "created":
+		meta.Created, err = time.Parse(time.RFC3339, value)
+	case "modified":
+		meta.Modified, err = time.Parse(time.RFC3339, value)
+	case "levels":
+		meta.Levels = nil
+		for _, field := range strings.Fields(value) {
+			level, err := strconv.Atoi(field)
+			if err != nil {
+				return err
+			}
+			meta.Levels = append(meta.Levels, level)
+		}
+	case "par":
+		meta.ParScore, err = strconv.Atoi(value)
+	case "thumbnail":
+		meta.Thumbnail, err = base64.StdEncoding.DecodeString(value)
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package maps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sunkink29/3dpacman/ghost"
+)
+
+func TestTextRoundTrip(t *testing.T) {
+	for name, curMap := range jsonTestMaps(t) {
+		data, err := curMap.encodeText()
+		if err != nil {
+			t.Fatal(name, ": ", err)
+		}
+		newMap, err := decodeText(data)
+		if err != nil {
+			t.Fatal(name, ": ", err)
+		}
+		size := curMap.GetSize()
+		if newSize := newMap.GetSize(); newSize != size {
+			t.Fatalf("%s: size is %v, want %v", name, newSize, size)
+		}
+		// wall directions are worked out again so only the types are kept
+		for layer := Layer(0); layer < NumLayers; layer++ {
+			for x := 0; x < size[0]; x++ {
+				for y := 0; y < size[1]; y++ {
+					pos := [2]int{x, y}
+					if got, want := newMap.GetLayerTile(layer, pos).Type, curMap.GetLayerTile(layer, pos).Type; got != want {
+						t.Fatalf("%s: %v tile at %v is %v, want %v", name, layer, pos, got, want)
+					}
+				}
+			}
+		}
+		if got, want := newMap.GetGhostSpawns(), curMap.GetGhostSpawns(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: ghost spawns are %v, want %v", name, got, want)
+		}
+		if got, want := newMap.GetMetadata(), curMap.GetMetadata(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: metadata is %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestTextGhostSpawns(t *testing.T) {
+	curMap := CreateEmptyMap([2]int{4, 3})
+	curMap.SetGhostSpawn(ghost.Inky, [2]int{3, 2})
+	curMap.SetGhostSpawn(ghost.Pinky, [2]int{0, 1})
+	data, err := curMap.encodeText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "ghost: pinky 0 1\nghost: inky 3 2\n") {
+		t.Errorf("ghost spawns are not in the header in order:\n%s", data)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"unknown ghost", "ghost: sue 1 1"},
+		{"missing position", "ghost: blinky 1"},
+		{"bad position", "ghost: blinky 1 x"},
+		{"outside of the map", "ghost: clyde 4 0"},
+		{"negative position", "ghost: clyde 0 -1"},
+	}
+	for _, test := range tests {
+		data := textHeader + "\nsize: 4 3\n" + test.header + "\n" + textSeparator + "\n"
+		if _, err := decodeText([]byte(data)); err == nil {
+			t.Errorf("%s: loaded without an error", test.name)
+		}
+	}
+
+	// the size may come after the ghost spawns
+	data = []byte(textHeader + "\nghost: clyde 3 2\nsize: 4 3\n" + textSeparator + "\n")
+	newMap, err := decodeText(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := newMap.GetGhostSpawns(), map[ghost.Personality][2]int{ghost.Clyde: {3, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ghost spawns are %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,399 @@
+// Package tiled imports maps made with the Tiled map editor, see https://www.mapeditor.org.
+//
+// Both the XML (.tmx) and JSON (.tmj) formats are read, with their tilesets either embedded or
+// in separate .tsx or .tsj files. Only orthogonal maps that are not infinite are supported.
+//
+// A tile gets its tile type from a custom string property named "type" holding a tile type name
+// such as "wall" or "dot". Without one the class of the tile is used as the name and if that is
+// empty too the ID of the tile in its tileset is used as the tile.TileType value, so a tileset
+// laid out in the same order as the tile types needs no properties at all.
+// Walls can set their directions with a "flags" property holding a list of direction names such
+// as "up,left" or the flags as a number. Walls without one are connected to the walls next to
+// them the same way the editor connects an auto wall.
+// A tile layer named after a map layer, such as "walls" or "decoration", puts its tiles on that
+// layer. The tiles of the other tile layers are put on the layer their type belongs to and when
+// there is more than one of them the tiles of the later layers replace the ones below.
+//
+// Objects in object layers are used as spawns by their name, or their class if they have no name.
+// "player" and "fruit" place the player or fruit spawn tile under the object on the triggers
+// layer, the name of a ghost such as "blinky" makes that ghost start there and "ghost" is given to
+// the first ghost that does not have a spawn yet. Other objects are ignored. A "player" object
+// replaces any player spawn in the tile layers and a map may only have one of them.
+// Tile and object layers inside group layers are read as if they were not in a group.
+//
+// The custom properties of the map fill in its metadata: "title", "author" and "description", "par"
+// for the par score and "levels" for a list of recommended levels such as "1,2,3".
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sunkink29/3dpacman/ghost"
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// the top bits of a global tile ID say if the tile is flipped or rotated, they are ignored
+const gidFlagMask = 0xF0000000
+
+// tiledMap holds what is needed from a map once it has been read from either format
+type tiledMap struct {
+	width, height         int
+	tileWidth, tileHeight int
+	tilesets              []tileset
+	layers                []tileLayer
+	objects               []object
+	// the custom properties of the map
+	properties map[string]string
+}
+
+type tileLayer struct {
+	name string
+	// the global tile IDs of the layer, row by row
+	gids []uint32
+}
+
+type tileset struct {
+	firstGID int
+	tiles    map[int]tileInfo
+}
+
+type tileInfo struct {
+	class      string
+	properties map[string]string
+}
+
+type object struct {
+	name, class         string
+	x, y, width, height float64
+	// set for tile objects, which are positioned by their bottom left corner
+	hasGID bool
+}
+
+// Import reads a Tiled map in the XML format if filename ends in .tmx and the JSON format otherwise
+func Import(filename string) (*maps.Map, error) {
+	if strings.EqualFold(filepath.Ext(filename), ".tmx") {
+		return ImportTMX(filename)
+	}
+	return ImportTMJ(filename)
+}
+
+// checkSize returns an error if the map is too small or too large to be a game map. It is checked
+// before the layers are read so their size is limited too.
+func (tMap *tiledMap) checkSize() error {
+	if tMap.width < 1 || tMap.height < 1 || tMap.width > maps.MaxMapSize || tMap.height > maps.MaxMapSize {
+		return errors.New(fmt.Sprint("Error importing map: bad map size ", tMap.width, "x", tMap.height))
+	}
+	return nil
+}
+
+// build turns a Tiled map into a game map
+func (tMap *tiledMap) build() (*maps.Map, error) {
+	if err := tMap.checkSize(); err != nil {
+		return nil, err
+	}
+	if len(tMap.layers) == 0 {
+		return nil, errors.New("Error importing map: map has no tile layers")
+	}
+	newMap := maps.CreateEmptyMap([2]int{tMap.width, tMap.height})
+	// tiles with flags set by the map are changed last so placing the tiles next to them does not
+	// change their flags
+	type flagged struct {
+		layer maps.Layer
+		pos   [2]int
+		flags tile.TileFlag
+	}
+	var flaggedTiles []flagged
+	for y := 0; y < tMap.height; y++ {
+		for x := 0; x < tMap.width; x++ {
+			gid := uint32(0)
+			for _, layer := range tMap.layers {
+				if _, named := layer.mapLayer(); named {
+					continue
+				}
+				if layerGID := layer.gids[y*tMap.width+x] &^ gidFlagMask; layerGID != 0 {
+					gid = layerGID
+				}
+			}
+			tileType, flags, err := tMap.lookupTile(gid)
+			if err != nil {
+				return nil, errors.New(fmt.Sprint("Error importing map: tile at ", x, ",", y, ": ", err))
+			}
+			newMap.ChangeMapTile([2]int{x, y}, tileType, 0)
+			if flags != 0 {
+				flaggedTiles = append(flaggedTiles, flagged{maps.LayerOf(tileType), [2]int{x, y}, flags})
+			}
+		}
+	}
+	for _, layer := range tMap.layers {
+		mapLayer, named := layer.mapLayer()
+		if !named {
+			continue
+		}
+		for i, gid := range layer.gids {
+			if gid &^= gidFlagMask; gid == 0 {
+				continue
+			}
+			pos := [2]int{i % tMap.width, i / tMap.width}
+			tileType, flags, err := tMap.lookupTile(gid)
+			if err != nil {
+				return nil, errors.New(fmt.Sprint("Error importing map: tile at ", pos[0], ",", pos[1], ": ", err))
+			}
+			if !newMap.SetTile(mapLayer, pos, tileType, 0) {
+				return nil, errors.New(fmt.Sprint("Error importing map: tile at ", pos[0], ",", pos[1], ": ", tileType, " can not be on the ", mapLayer, " layer"))
+			}
+			if flags != 0 {
+				flaggedTiles = append(flaggedTiles, flagged{mapLayer, pos, flags})
+			}
+		}
+	}
+	for _, flaggedTile := range flaggedTiles {
+		newMap.SetTileFlags(flaggedTile.layer, flaggedTile.pos, flaggedTile.flags)
+	}
+
+	nextGhost := ghost.Blinky
+	spawned := make(map[ghost.Personality]bool)
+	playerPlaced := false
+	for _, obj := range tMap.objects {
+		pos, ok := tMap.objectTile(obj)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(obj.name)
+		if name == "" {
+			name = strings.ToLower(obj.class)
+		}
+		switch name {
+		case "player":
+			if playerPlaced {
+				return nil, errors.New("Error importing map: map has more than one player object")
+			}
+			playerPlaced = true
+			// the object replaces any player spawn placed by the tile layers
+			for x := 0; x < tMap.width; x++ {
+				for y := 0; y < tMap.height; y++ {
+					if newMap.GetLayerTile(maps.Triggers, [2]int{x, y}).Type == tile.PlayerSpawn {
+						newMap.SetTile(maps.Triggers, [2]int{x, y}, tile.Blank, 0)
+					}
+				}
+			}
+			newMap.SetTile(maps.Triggers, pos, tile.PlayerSpawn, 0)
+		case "fruit":
+			newMap.SetTile(maps.Triggers, pos, tile.FruitSpawn, 0)
+		case "ghost":
+			for nextGhost <= ghost.Clyde && spawned[nextGhost] {
+				nextGhost++
+			}
+			if nextGhost <= ghost.Clyde {
+				newMap.SetGhostSpawn(nextGhost, pos)
+				spawned[nextGhost] = true
+			}
+		default:
+			if personality, ok := ghost.ParsePersonality(name); ok {
+				newMap.SetGhostSpawn(personality, pos)
+				spawned[personality] = true
+			}
+		}
+	}
+	if err := tMap.setMetadata(&newMap); err != nil {
+		return nil, err
+	}
+	newMap.Restart()
+	return &newMap, nil
+}
+
+// setMetadata fills in the metadata of the map from the custom properties of the Tiled map
+func (tMap *tiledMap) setMetadata(newMap *maps.Map) error {
+	meta := newMap.GetMetadata()
+	meta.Title = tMap.properties["title"]
+	meta.Author = tMap.properties["author"]
+	meta.Description = tMap.properties["description"]
+	if par := strings.TrimSpace(tMap.properties["par"]); par != "" {
+		var err error
+		if meta.ParScore, err = strconv.Atoi(par); err != nil {
+			return errors.New(fmt.Sprintf("Error importing map: bad par score %q", par))
+		}
+	}
+	for _, field := range strings.FieldsFunc(tMap.properties["levels"], func(r rune) bool { return r == ',' || r == ' ' }) {
+		level, err := strconv.Atoi(field)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error importing map: bad level %q", field))
+		}
+		meta.Levels = append(meta.Levels, level)
+	}
+	if err := newMap.SetMetadata(meta); err != nil {
+		return errors.New(fmt.Sprint("Error importing map: ", err))
+	}
+	return nil
+}
+
+// mapLayer returns the map layer a tile layer is named after and false if it is not named after one
+func (layer tileLayer) mapLayer() (maps.Layer, bool) {
+	return maps.ParseLayer(strings.ToLower(layer.name))
+}
+
+// lookupTile returns the tile type and flags for a global tile ID
+func (tMap *tiledMap) lookupTile(gid uint32) (tile.TileType, tile.TileFlag, error) {
+	if gid == 0 {
+		return tile.Blank, 0, nil
+	}
+	var set *tileset
+	for i := range tMap.tilesets {
+		if uint32(tMap.tilesets[i].firstGID) <= gid && (set == nil || tMap.tilesets[i].firstGID > set.firstGID) {
+			set = &tMap.tilesets[i]
+		}
+	}
+	if set == nil {
+		return tile.Blank, 0, errors.New(fmt.Sprint("tile ID ", gid, " is not in any tileset"))
+	}
+	id := int(gid) - set.firstGID
+	info := set.tiles[id]
+
+	var tileType tile.TileType
+	typeName, hasType := info.properties["type"]
+	if !hasType {
+		typeName, hasType = info.class, info.class != ""
+	}
+	if hasType {
+		var ok bool
+		if tileType, ok = tile.ParseTileType(typeName); !ok {
+			return tile.Blank, 0, errors.New(fmt.Sprintf("unknown tile type %q", typeName))
+		}
+	} else {
+		tileType = tile.TileType(id)
+		if id > math.MaxUint16 || !tileType.IsValid() {
+			return tile.Blank, 0, errors.New(fmt.Sprint("tile ", id, " has no type and is not a tile type"))
+		}
+	}
+
+	flags, err := parseFlags(info.properties["flags"])
+	return tileType, flags, err
+}
+
+// parseFlags reads a flags property that is either a number or a list of direction names
+func parseFlags(value string) (tile.TileFlag, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return 0, nil
+	}
+	if number, err := strconv.ParseUint(value, 0, 16); err == nil {
+		return tile.TileFlag(number), nil
+	}
+	var flags tile.TileFlag
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '|' }) {
+		flag, ok := tile.ParseTileFlag(strings.ToLower(name))
+		if !ok {
+			return 0, errors.New(fmt.Sprintf("unknown flag %q", name))
+		}
+		flags |= flag
+	}
+	return flags, nil
+}
+
+// objectTile returns the tile under the centre of an object and false if it is off the map
+func (tMap *tiledMap) objectTile(obj object) ([2]int, bool) {
+	if tMap.tileWidth <= 0 || tMap.tileHeight <= 0 {
+		return [2]int{}, false
+	}
+	y := obj.y
+	if obj.hasGID {
+		y -= obj.height
+	}
+	pos := [2]int{
+		int(math.Floor((obj.x + obj.width/2) / float64(tMap.tileWidth))),
+		int(math.Floor((y + obj.height/2) / float64(tMap.tileHeight))),
+	}
+	return pos, pos[0] >= 0 && pos[1] >= 0 && pos[0] < tMap.width && pos[1] < tMap.height
+}
+
+// decodeLayerData reads the tile IDs of a layer saved as base64, optionally compressed, or as csv
+func decodeLayerData(data string, encoding string, compression string, count int) ([]uint32, error) {
+	var gids []uint32
+	switch encoding {
+	case "csv":
+		for _, field := range strings.Split(data, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			gid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("bad tile ID %q", field))
+			}
+			gids = append(gids, uint32(gid))
+		}
+	case "base64":
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = decompress(raw, compression, count*4); err != nil {
+			return nil, err
+		}
+		if len(raw)%4 != 0 {
+			return nil, errors.New("layer data is not a whole number of tiles")
+		}
+		for i := 0; i < len(raw); i += 4 {
+			gids = append(gids, binary.LittleEndian.Uint32(raw[i:]))
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported layer encoding %q", encoding))
+	}
+	if len(gids) != count {
+		return nil, errors.New(fmt.Sprint("layer has ", len(gids), " tiles but the map has ", count))
+	}
+	return gids, nil
+}
+
+// decompress unpacks layer data and returns an error if it unpacks to more than limit bytes
+func decompress(data []byte, compression string, limit int) ([]byte, error) {
+	var reader io.Reader
+	switch compression {
+	case "":
+		return data, nil
+	case "zlib":
+		zlibReader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		reader = zlibReader
+	case "gzip":
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		reader = gzipReader
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported layer compression %q", compression))
+	}
+	// stop reading once the data is too long instead of unpacking all of it
+	raw, err := ioutil.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > limit {
+		return nil, errors.New(fmt.Sprint("layer data unpacks to more than the ", limit, " bytes the map needs"))
+	}
+	return raw, nil
+}
+
+// checkHeader returns an error for the kinds of Tiled maps that can not be imported
+func checkHeader(orientation string, infinite bool) error {
+	if orientation != "" && orientation != "orthogonal" {
+		return errors.New(fmt.Sprint("Error importing map: ", orientation, " maps are not supported"))
+	}
+	if infinite {
+		return errors.New("Error importing map: infinite maps are not supported")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// the tiles have no properties so each global ID is the tile type plus one: 2 is a wall, 3 a dot
+// and 6 a player spawn. The decoration layer and the player object are in a group layer.
+const testTMX = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="3" height="2" tilewidth="16" tileheight="16" infinite="0">
+ <tileset firstgid="1" name="tiles" tilewidth="16" tileheight="16" tilecount="16" columns="4"/>
+ <layer id="1" name="base" width="3" height="2">
+  <data encoding="csv">2,3,2,3,6,3</data>
+ </layer>
+ <group id="2" name="extras">
+  <properties>
+   <property name="note" value="not a layer"/>
+  </properties>
+  <layer id="3" name="decoration" width="3" height="2">
+   <data encoding="csv">0,0,2,0,0,0</data>
+  </layer>
+  <objectgroup id="4" name="spawns">
+   <object id="1" name="player" x="16" y="0" width="16" height="16"/>
+  </objectgroup>
+ </group>
+</map>
+`
+
+const testTMJ = `{
+ "orientation": "orthogonal", "width": 3, "height": 2, "tilewidth": 16, "tileheight": 16, "infinite": false,
+ "tilesets": [{"firstgid": 1, "name": "tiles"}],
+ "layers": [
+  {"type": "tilelayer", "name": "base", "data": [2, 3, 2, 3, 6, 3]},
+  {"type": "group", "name": "extras", "layers": [
+   {"type": "tilelayer", "name": "decoration", "data": [0, 0, 2, 0, 0, 0]},
+   {"type": "objectgroup", "name": "spawns", "objects": [{"name": "player", "x": 16, "y": 0, "width": 16, "height": 16}]}
+  ]}
+ ]
+}
+`
+
+// tempDir returns a new directory for the files of a test and a function that removes it
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "tiled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// importString writes data to a file in dir with the given name and imports it
+func importString(t *testing.T, dir string, name string, data string) (*maps.Map, error) {
+	filename := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Import(filename)
+}
+
+func TestImportGroupsAndPlayer(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	tmxMap, err := importString(t, dir, "test.tmx", testTMX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmjMap, err := importString(t, dir, "test.tmj", testTMJ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, newMap := range []*maps.Map{tmxMap, tmjMap} {
+		if got := newMap.GetLayerTile(maps.Decoration, [2]int{2, 0}).Type; got != tile.Wall {
+			t.Errorf("decoration tile in the group is %v, want wall", got)
+		}
+		if got := newMap.GetPlayerSpawn(); got != [2]int{1, 0} {
+			t.Errorf("player spawn is %v, want 1,0 from the object", got)
+		}
+		if got := newMap.GetLayerTile(maps.Triggers, [2]int{1, 1}).Type; got != tile.Blank {
+			t.Errorf("player spawn from the tile layer was left as %v", got)
+		}
+	}
+	for layer := maps.Layer(0); layer < maps.NumLayers; layer++ {
+		for x := 0; x < 3; x++ {
+			for y := 0; y < 2; y++ {
+				pos := [2]int{x, y}
+				if got, want := tmjMap.GetLayerTile(layer, pos), tmxMap.GetLayerTile(layer, pos); got.Type != want.Type || got.Flags != want.Flags {
+					t.Errorf("%v tile at %v is %v %v in the .tmj and %v %v in the .tmx", layer, pos, got.Type, got.Flags, want.Type, want.Flags)
+				}
+			}
+		}
+	}
+}
+
+func TestImportTwoPlayers(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	secondPlayer := `<object id="2" name="player" x="0" y="16" width="16" height="16"/>
+  </objectgroup>`
+	data := strings.Replace(testTMX, "</objectgroup>", secondPlayer, 1)
+	if _, err := importString(t, dir, "test.tmx", data); err == nil {
+		t.Error("map with two player objects imported without an error")
+	}
+}
+
+// packLayer returns the tile IDs as base64 layer data packed with compression
+func packLayer(t *testing.T, compression string, gids []uint32) string {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	if compression == "zlib" {
+		writer = zlib.NewWriter(&buf)
+	} else {
+		writer = gzip.NewWriter(&buf)
+	}
+	for _, gid := range gids {
+		if err := binary.Write(writer, binary.LittleEndian, gid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestImportCompressedLayer(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	csvData := `<data encoding="csv">2,3,2,3,6,3</data>`
+	for _, compression := range []string{"zlib", "gzip"} {
+		layerData := `<data encoding="base64" compression="` + compression + `">` + packLayer(t, compression, []uint32{2, 3, 2, 3, 6, 3}) + `</data>`
+		newMap, err := importString(t, dir, "test.tmx", strings.Replace(testTMX, csvData, layerData, 1))
+		if err != nil {
+			t.Fatal(compression, ": ", err)
+		}
+		if got := newMap.GetLayerTile(maps.Pickups, [2]int{1, 0}).Type; got != tile.Dot {
+			t.Errorf("%s: tile at 1,0 is %v, want dot", compression, got)
+		}
+
+		// data that unpacks to far more than the map needs is not unpacked in full
+		layerData = `<data encoding="base64" compression="` + compression + `">` + packLayer(t, compression, make([]uint32, 1<<20)) + `</data>`
+		if _, err := importString(t, dir, "test.tmx", strings.Replace(testTMX, csvData, layerData, 1)); err == nil {
+			t.Errorf("%s: layer with too much data imported without an error", compression)
+		}
+	}
+}
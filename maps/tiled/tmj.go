@@ -0,0 +1,177 @@
+package tiled
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/sunkink29/3dpacman/maps"
+)
+
+type tmjMap struct {
+	Orientation string        `json:"orientation"`
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	TileWidth   int           `json:"tilewidth"`
+	TileHeight  int           `json:"tileheight"`
+	Infinite    bool          `json:"infinite"`
+	Tilesets    []tmjTileset  `json:"tilesets"`
+	Layers      []tmjLayer    `json:"layers"`
+	Properties  []tmjProperty `json:"properties"`
+}
+
+type tmjTileset struct {
+	FirstGID int       `json:"firstgid"`
+	Source   string    `json:"source"`
+	Tiles    []tmjTile `json:"tiles"`
+}
+
+type tmjTile struct {
+	ID         int           `json:"id"`
+	Type       string        `json:"type"`
+	Class      string        `json:"class"`
+	Properties []tmjProperty `json:"properties"`
+}
+
+type tmjProperty struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+func (property tmjProperty) value() string {
+	if value, ok := property.Value.(string); ok {
+		return value
+	}
+	return fmt.Sprint(property.Value)
+}
+
+type tmjLayer struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Encoding    string `json:"encoding"`
+	Compression string `json:"compression"`
+	// an array of tile IDs or a base64 string depending on the encoding
+	Data    json.RawMessage `json:"data"`
+	Objects []struct {
+		Name   string  `json:"name"`
+		Type   string  `json:"type"`
+		Class  string  `json:"class"`
+		GID    uint32  `json:"gid"`
+		X      float64 `json:"x"`
+		Y      float64 `json:"y"`
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	} `json:"objects"`
+	// the layers inside a group layer
+	Layers []tmjLayer `json:"layers"`
+}
+
+// ImportTMJ reads a Tiled map saved in the JSON format
+func ImportTMJ(filename string) (*maps.Map, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprint("Error Reading Map:", err))
+	}
+	var jMap tmjMap
+	if err := json.Unmarshal(data, &jMap); err != nil {
+		return nil, errors.New(fmt.Sprint("Error importing map: ", err))
+	}
+	if err := checkHeader(jMap.Orientation, jMap.Infinite); err != nil {
+		return nil, err
+	}
+
+	tMap := tiledMap{width: jMap.Width, height: jMap.Height, tileWidth: jMap.TileWidth, tileHeight: jMap.TileHeight}
+	tMap.properties = make(map[string]string, len(jMap.Properties))
+	for _, property := range jMap.Properties {
+		tMap.properties[property.Name] = property.value()
+	}
+	for _, jSet := range jMap.Tilesets {
+		if jSet.Source != "" {
+			set, err := loadTileset(filepath.Join(filepath.Dir(filename), jSet.Source))
+			if err != nil {
+				return nil, err
+			}
+			set.firstGID = jSet.FirstGID
+			tMap.tilesets = append(tMap.tilesets, set)
+			continue
+		}
+		tMap.tilesets = append(tMap.tilesets, jSet.toTileset())
+	}
+	if err := tMap.checkSize(); err != nil {
+		return nil, err
+	}
+	if err := tMap.addTMJLayers(jMap.Layers); err != nil {
+		return nil, err
+	}
+	return tMap.build()
+}
+
+// addTMJLayers adds the tile and object layers in layers and in any group layers inside it
+func (tMap *tiledMap) addTMJLayers(layers []tmjLayer) error {
+	for i, layer := range layers {
+		switch layer.Type {
+		case "tilelayer":
+			var gids []uint32
+			if layer.Encoding == "base64" {
+				var text string
+				if err := json.Unmarshal(layer.Data, &text); err != nil {
+					return errors.New(fmt.Sprint("Error importing map: layer ", i, ": ", err))
+				}
+				var err error
+				if gids, err = decodeLayerData(text, layer.Encoding, layer.Compression, tMap.width*tMap.height); err != nil {
+					return errors.New(fmt.Sprint("Error importing map: layer ", i, ": ", err))
+				}
+			} else {
+				if err := json.Unmarshal(layer.Data, &gids); err != nil {
+					return errors.New(fmt.Sprint("Error importing map: layer ", i, ": ", err))
+				}
+				if len(gids) != tMap.width*tMap.height {
+					return errors.New(fmt.Sprint("Error importing map: layer ", i, " has ", len(gids), " tiles but the map has ", tMap.width*tMap.height))
+				}
+			}
+			tMap.layers = append(tMap.layers, tileLayer{layer.Name, gids})
+		case "objectgroup":
+			for _, jObj := range layer.Objects {
+				class := jObj.Class
+				if class == "" {
+					class = jObj.Type
+				}
+				tMap.objects = append(tMap.objects, object{jObj.Name, class, jObj.X, jObj.Y, jObj.Width, jObj.Height, jObj.GID != 0})
+			}
+		case "group":
+			if err := tMap.addTMJLayers(layer.Layers); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func parseTMJTileset(data []byte) (tileset, error) {
+	var jSet tmjTileset
+	if err := json.Unmarshal(data, &jSet); err != nil {
+		return tileset{}, errors.New(fmt.Sprint("Error importing tileset: ", err))
+	}
+	return jSet.toTileset(), nil
+}
+
+func (jSet tmjTileset) toTileset() tileset {
+	set := tileset{firstGID: jSet.FirstGID, tiles: make(map[int]tileInfo, len(jSet.Tiles))}
+	for _, jTile := range jSet.Tiles {
+		info := tileInfo{class: jTile.Class, properties: make(map[string]string, len(jTile.Properties))}
+		if info.class == "" {
+			info.class = jTile.Type
+		}
+		for _, property := range jTile.Properties {
+			if value, ok := property.Value.(string); ok {
+				info.properties[property.Name] = value
+			} else {
+				info.properties[property.Name] = fmt.Sprint(property.Value)
+			}
+		}
+		set.tiles[jTile.ID] = info
+	}
+	return set
+}
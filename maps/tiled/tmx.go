@@ -0,0 +1,192 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/sunkink29/3dpacman/maps"
+)
+
+type tmxMap struct {
+	Orientation string        `xml:"orientation,attr"`
+	Width       int           `xml:"width,attr"`
+	Height      int           `xml:"height,attr"`
+	TileWidth   int           `xml:"tilewidth,attr"`
+	TileHeight  int           `xml:"tileheight,attr"`
+	Infinite    int           `xml:"infinite,attr"`
+	Properties  []tmxProperty `xml:"properties>property"`
+	Tilesets    []tmxTileset  `xml:"tileset"`
+	// the layers in the order they are drawn along with any other elements, which are skipped
+	Layers []tmxLayer `xml:",any"`
+}
+
+type tmxTileset struct {
+	FirstGID int       `xml:"firstgid,attr"`
+	Source   string    `xml:"source,attr"`
+	Tiles    []tmxTile `xml:"tile"`
+}
+
+type tmxTile struct {
+	ID         int           `xml:"id,attr"`
+	Type       string        `xml:"type,attr"`
+	Class      string        `xml:"class,attr"`
+	Properties []tmxProperty `xml:"properties>property"`
+}
+
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+	// multi-line string properties keep their value here instead
+	Text string `xml:",chardata"`
+}
+
+func (property tmxProperty) value() string {
+	if property.Value == "" {
+		return property.Text
+	}
+	return property.Value
+}
+
+// tmxLayer is a tile layer, object layer or group layer, told apart by the name of its element
+type tmxLayer struct {
+	XMLName xml.Name
+	Name    string `xml:"name,attr"`
+	Data    struct {
+		Encoding    string `xml:"encoding,attr"`
+		Compression string `xml:"compression,attr"`
+		Text        string `xml:",chardata"`
+		// layers saved without an encoding list each tile as an element
+		Tiles []struct {
+			GID uint32 `xml:"gid,attr"`
+		} `xml:"tile"`
+	} `xml:"data"`
+	Objects []struct {
+		Name   string  `xml:"name,attr"`
+		Type   string  `xml:"type,attr"`
+		Class  string  `xml:"class,attr"`
+		GID    uint32  `xml:"gid,attr"`
+		X      float64 `xml:"x,attr"`
+		Y      float64 `xml:"y,attr"`
+		Width  float64 `xml:"width,attr"`
+		Height float64 `xml:"height,attr"`
+	} `xml:"object"`
+	// the layers inside a group layer along with any other elements, which are skipped
+	Layers []tmxLayer `xml:",any"`
+}
+
+// ImportTMX reads a Tiled map saved in the XML format
+func ImportTMX(filename string) (*maps.Map, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprint("Error Reading Map:", err))
+	}
+	var xMap tmxMap
+	if err := xml.Unmarshal(data, &xMap); err != nil {
+		return nil, errors.New(fmt.Sprint("Error importing map: ", err))
+	}
+	if err := checkHeader(xMap.Orientation, xMap.Infinite != 0); err != nil {
+		return nil, err
+	}
+
+	tMap := tiledMap{width: xMap.Width, height: xMap.Height, tileWidth: xMap.TileWidth, tileHeight: xMap.TileHeight}
+	tMap.properties = make(map[string]string, len(xMap.Properties))
+	for _, property := range xMap.Properties {
+		tMap.properties[property.Name] = property.value()
+	}
+	for _, xSet := range xMap.Tilesets {
+		if xSet.Source != "" {
+			set, err := loadTileset(filepath.Join(filepath.Dir(filename), xSet.Source))
+			if err != nil {
+				return nil, err
+			}
+			set.firstGID = xSet.FirstGID
+			tMap.tilesets = append(tMap.tilesets, set)
+			continue
+		}
+		tMap.tilesets = append(tMap.tilesets, xSet.toTileset())
+	}
+	if err := tMap.checkSize(); err != nil {
+		return nil, err
+	}
+	if err := tMap.addTMXLayers(xMap.Layers); err != nil {
+		return nil, err
+	}
+	return tMap.build()
+}
+
+// addTMXLayers adds the tile and object layers in layers and in any group layers inside it
+func (tMap *tiledMap) addTMXLayers(layers []tmxLayer) error {
+	count := tMap.width * tMap.height
+	for i, layer := range layers {
+		switch layer.XMLName.Local {
+		case "layer":
+			var gids []uint32
+			if layer.Data.Encoding == "" {
+				for _, xTile := range layer.Data.Tiles {
+					gids = append(gids, xTile.GID)
+				}
+				if len(gids) != count {
+					return errors.New(fmt.Sprint("Error importing map: layer ", i, " has ", len(gids), " tiles but the map has ", count))
+				}
+			} else {
+				var err error
+				if gids, err = decodeLayerData(layer.Data.Text, layer.Data.Encoding, layer.Data.Compression, count); err != nil {
+					return errors.New(fmt.Sprint("Error importing map: layer ", i, ": ", err))
+				}
+			}
+			tMap.layers = append(tMap.layers, tileLayer{layer.Name, gids})
+		case "objectgroup":
+			for _, xObj := range layer.Objects {
+				class := xObj.Class
+				if class == "" {
+					class = xObj.Type
+				}
+				tMap.objects = append(tMap.objects, object{xObj.Name, class, xObj.X, xObj.Y, xObj.Width, xObj.Height, xObj.GID != 0})
+			}
+		case "group":
+			if err := tMap.addTMXLayers(layer.Layers); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadTileset reads a tileset saved in its own file in either format
+func loadTileset(filename string) (tileset, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return tileset{}, errors.New(fmt.Sprint("Error Reading Tileset:", err))
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".tsx") {
+		var xSet tmxTileset
+		if err := xml.Unmarshal(data, &xSet); err != nil {
+			return tileset{}, errors.New(fmt.Sprint("Error importing tileset: ", err))
+		}
+		return xSet.toTileset(), nil
+	}
+	return parseTMJTileset(data)
+}
+
+func (xSet tmxTileset) toTileset() tileset {
+	set := tileset{firstGID: xSet.FirstGID, tiles: make(map[int]tileInfo, len(xSet.Tiles))}
+	for _, xTile := range xSet.Tiles {
+		info := tileInfo{class: xTile.Class, properties: make(map[string]string, len(xTile.Properties))}
+		if info.class == "" {
+			info.class = xTile.Type
+		}
+		for _, property := range xTile.Properties {
+			value := property.Value
+			if value == "" {
+				value = property.Text
+			}
+			info.properties[property.Name] = value
+		}
+		set.tiles[xTile.ID] = info
+	}
+	return set
+}
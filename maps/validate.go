@@ -0,0 +1,227 @@
+package maps
+
+import (
+	"fmt"
+
+	"github.com/sunkink29/3dpacman/ghost"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+// IssueKind is the kind of problem Validate found
+type IssueKind int
+
+const (
+	// MissingPlayerSpawn maps start the player on a made up tile that may be inside a wall
+	MissingPlayerSpawn IssueKind = iota
+	// DuplicatePlayerSpawn maps only use the first player spawn
+	DuplicatePlayerSpawn
+	// DuplicateFruitSpawn maps only use the first fruit spawn
+	DuplicateFruitSpawn
+	// BlockedGhostSpawn ghosts are given a spawn inside a wall or outside of the map
+	BlockedGhostSpawn
+	// UnreachableDot dots can not be eaten so the level can never be finished
+	UnreachableDot
+	// OpenBorder tiles are open tiles on the edge of the map the player can reach that are not tunnels
+	OpenBorder
+	// InconsistentWall walls are connected to a tile that is not connected back
+	InconsistentWall
+	// UnpairedTunnel tunnels do not have a tunnel on the opposite edge of the map to lead to
+	UnpairedTunnel
+	// MissingGhostHouse maps start every ghost on the open tile closest to the centre
+	MissingGhostHouse
+	// MissingGhostDoor maps have a ghost house the ghosts can not leave through a door
+	MissingGhostDoor
+)
+
+var issueKindNames = [...]string{
+	"missing player spawn",
+	"duplicate player spawn",
+	"duplicate fruit spawn",
+	"blocked ghost spawn",
+	"unreachable dot",
+	"open border",
+	"inconsistent wall",
+	"unpaired tunnel",
+	"missing ghost house",
+	"missing ghost door",
+}
+
+func (kind IssueKind) String() string {
+	if kind < 0 || int(kind) >= len(issueKindNames) {
+		return "unknown issue"
+	}
+	return issueKindNames[kind]
+}
+
+// noPos is the position of issues with the whole map instead of a single tile
+var noPos = [2]int{-1, -1}
+
+// Issue is a problem with a map found by Validate
+type Issue struct {
+	Kind IssueKind
+	// the tile the issue is on or {-1, -1} if it is about the whole map
+	Pos     [2]int
+	Message string
+}
+
+func (issue Issue) String() string {
+	if issue.Pos == noPos {
+		return issue.Message
+	}
+	return fmt.Sprint(issue.Pos[0], ",", issue.Pos[1], ": ", issue.Message)
+}
+
+// Validate checks a map for mistakes that still let it load but make it play wrong: missing or
+// duplicate spawns, dots the player can not reach, wall flags that do not match the walls next to
+// them, openings in the edge of the map, tunnels without a partner and a missing ghost house.
+// The issues are returned in the order of their kind and then column by column, nil if there are none.
+func Validate(curMap *Map) []Issue {
+	var issues []Issue
+	add := func(kind IssueKind, pos [2]int, format string, args ...interface{}) {
+		issues = append(issues, Issue{kind, pos, fmt.Sprintf(format, args...)})
+	}
+	size := curMap.GetSize()
+
+	playerSpawns := curMap.findTiles(Triggers, tile.PlayerSpawn)
+	if len(playerSpawns) == 0 {
+		spawn := curMap.GetPlayerSpawn()
+		add(MissingPlayerSpawn, noPos, "no player spawn, the player starts on %d,%d", spawn[0], spawn[1])
+	}
+	for i := 1; i < len(playerSpawns); i++ {
+		add(DuplicatePlayerSpawn, playerSpawns[i], "player spawn is not used, the player starts on %d,%d", playerSpawns[0][0], playerSpawns[0][1])
+	}
+	fruitSpawns := curMap.findTiles(Triggers, tile.FruitSpawn)
+	for i := 1; i < len(fruitSpawns); i++ {
+		add(DuplicateFruitSpawn, fruitSpawns[i], "fruit spawn is not used, fruit appear on %d,%d", fruitSpawns[0][0], fruitSpawns[0][1])
+	}
+	for personality := ghost.Blinky; personality <= ghost.Clyde; personality++ {
+		pos, ok := curMap.ghostSpawns[personality]
+		if !ok {
+			continue
+		}
+		if !curMap.inBounds(pos) {
+			add(BlockedGhostSpawn, noPos, "%s spawns outside of the map on %d,%d", personality, pos[0], pos[1])
+		} else if curMap.getTileType(pos) == tile.Wall {
+			add(BlockedGhostSpawn, pos, "%s spawns inside a wall", personality)
+		}
+	}
+
+	if len(playerSpawns) > 0 {
+		reachable := curMap.reachableFrom(playerSpawns[0])
+		for _, pos := range curMap.findTiles(Pickups, tile.Dot, tile.DotBig) {
+			if !reachable[pos[0]][pos[1]] {
+				add(UnreachableDot, pos, "%s can not be reached from the player spawn", curMap.layers[Pickups][pos[0]][pos[1]].Type)
+			}
+		}
+		for x := 0; x < size[0]; x++ {
+			for y := 0; y < size[1]; y++ {
+				onBorder := x == 0 || y == 0 || x == size[0]-1 || y == size[1]-1
+				if onBorder && reachable[x][y] && curMap.getTileType([2]int{x, y}) != tile.Tunnel {
+					add(OpenBorder, [2]int{x, y}, "the player can reach the edge of the map")
+				}
+			}
+		}
+	}
+
+	for _, pos := range curMap.findTiles(Walls, tile.Wall) {
+		flags := curMap.layers[Walls][pos[0]][pos[1]].Flags
+		for _, side := range wallSides {
+			if flags&side.flag == 0 {
+				continue
+			}
+			next := [2]int{pos[0] + side.dir[0], pos[1] + side.dir[1]}
+			if next[0] < 0 || next[1] < 0 || next[0] >= size[0] || next[1] >= size[1] {
+				add(InconsistentWall, pos, "wall connects %s off the edge of the map", side.name)
+				continue
+			}
+			nextTile := curMap.layers[Walls][next[0]][next[1]]
+			if nextTile.Type != tile.Wall {
+				add(InconsistentWall, pos, "wall connects %s to %s", side.name, nextTile.Type)
+			} else if nextTile.Flags&side.opposite == 0 {
+				add(InconsistentWall, pos, "wall connects %s to a wall that does not connect back", side.name)
+			}
+		}
+	}
+
+	for _, pos := range curMap.findTiles(Floor, tile.Tunnel) {
+		if !curMap.hasTunnelPartner(pos) {
+			add(UnpairedTunnel, pos, "tunnel does not lead to a tunnel on the opposite edge of the map")
+		}
+	}
+
+	if len(curMap.findTiles(Floor, tile.GhostHouse)) == 0 {
+		spawn := curMap.GetGhostSpawn()
+		add(MissingGhostHouse, noPos, "no ghost house, ghosts start on %d,%d", spawn[0], spawn[1])
+	} else if _, _, ok := curMap.GetGhostDoor(); !ok {
+		add(MissingGhostDoor, noPos, "the ghost house has no door with an open tile outside it")
+	}
+	return issues
+}
+
+// the direction flags of a wall and the tile each of them connects to
+var wallSides = [4]struct {
+	flag, opposite tile.TileFlag
+	dir            [2]int
+	name           string
+}{
+	{tile.Up, tile.Down, [2]int{0, -1}, "up"},
+	{tile.Down, tile.Up, [2]int{0, 1}, "down"},
+	{tile.Left, tile.Right, [2]int{-1, 0}, "left"},
+	{tile.Right, tile.Left, [2]int{1, 0}, "right"},
+}
+
+// findTiles returns the positions of the tiles of the given types on a layer, column by column
+func (curMap *Map) findTiles(layer Layer, tileTypes ...tile.TileType) [][2]int {
+	var found [][2]int
+	for i, col := range curMap.layers[layer] {
+		for j, cTile := range col {
+			for _, tileType := range tileTypes {
+				if cTile.Type == tileType {
+					found = append(found, [2]int{i, j})
+					break
+				}
+			}
+		}
+	}
+	return found
+}
+
+// reachableFrom returns which tiles the player can walk to from start, going through tunnels but
+// not the ghost house door
+func (curMap *Map) reachableFrom(start [2]int) [][]bool {
+	size := curMap.GetSize()
+	reachable := make([][]bool, size[0])
+	for i := range reachable {
+		reachable[i] = make([]bool, size[1])
+	}
+	reachable[start[0]][start[1]] = true
+	queue := [][2]int{start}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		for _, side := range wallSides {
+			if !curMap.moveMap.CanMove(pos, side.dir, false) {
+				continue
+			}
+			next := curMap.moveMap.Target(pos, side.dir)
+			if !reachable[next[0]][next[1]] {
+				reachable[next[0]][next[1]] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return reachable
+}
+
+// hasTunnelPartner returns true if stepping off the edge of the map from the tunnel at pos leads
+// to another tunnel
+func (curMap *Map) hasTunnelPartner(pos [2]int) bool {
+	size := curMap.GetSize()
+	for _, side := range wallSides {
+		next := [2]int{pos[0] + side.dir[0], pos[1] + side.dir[1]}
+		if _, ok := tile.TunnelExit(next, size, curMap.getTileType); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package maps
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sunkink29/3dpacman/ghost"
+)
+
+func TestValidateShippedMaps(t *testing.T) {
+	var filenames []string
+	for _, ext := range []string{".tmap", TextExtension} {
+		matches, err := filepath.Glob(filepath.Join("../assets/maps", "*"+ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		filenames = append(filenames, matches...)
+	}
+	if len(filenames) == 0 {
+		t.Fatal("no maps in ../assets/maps")
+	}
+	for _, filename := range filenames {
+		curMap, err := Load(filename)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		for _, issue := range Validate(curMap) {
+			t.Errorf("%s: %s: %s", filename, issue.Kind, issue)
+		}
+	}
+}
+
+func TestValidateGhostSpawnOutside(t *testing.T) {
+	curMap := CreateEmptyMap([2]int{5, 4})
+	// spawns can only get outside of the map from inside the package
+	curMap.ghostSpawns = map[ghost.Personality][2]int{ghost.Blinky: {5, -3}}
+	found := false
+	for _, issue := range Validate(&curMap) {
+		if issue.Kind == BlockedGhostSpawn {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("ghost spawn outside of the map was not reported")
+	}
+}
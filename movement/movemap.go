@@ -0,0 +1,139 @@
+package movement
+
+import "github.com/sunkink29/3dpacman/tile"
+
+// MoveMap holds for every tile the directions an entity standing on it can move in so movement
+// does not need to look at the tiles around it. Each tile has a byte where the low four bits are
+// the directions anything can move in and the high four bits are the directions only ghosts
+// leaving the ghost house can move in, through the ghost house door. The bits in each half are
+// up, down, left and right starting from the lowest bit.
+// A direction leading off the edge of the map is open when it goes through a tunnel.
+type MoveMap struct {
+	size  [2]int
+	masks [][]uint8
+}
+
+func NewMoveMap(size [2]int) MoveMap {
+	masks := make([][]uint8, size[0])
+	for i := range masks {
+		masks[i] = make([]uint8, size[1])
+	}
+	return MoveMap{size, masks}
+}
+
+// BuildMoveMap works out the directions that can be moved in from every tile on a map
+func BuildMoveMap(size [2]int, getTileType func(pos [2]int) tile.TileType) MoveMap {
+	moves := NewMoveMap(size)
+	for i, col := range moves.masks {
+		for j := range col {
+			moves.masks[i][j] = calcMask([2]int{i, j}, size, getTileType)
+		}
+	}
+	return moves
+}
+
+func (moves *MoveMap) GetSize() [2]int {
+	return moves.size
+}
+
+// GetMask returns the byte holding the directions that can be moved in from pos
+func (moves *MoveMap) GetMask(pos [2]int) uint8 {
+	if !moves.onMap(pos) {
+		return 0
+	}
+	return moves.masks[pos[0]][pos[1]]
+}
+
+// SetMask replaces the directions that can be moved in from pos
+func (moves *MoveMap) SetMask(pos [2]int, mask uint8) {
+	if moves.onMap(pos) {
+		moves.masks[pos[0]][pos[1]] = mask
+	}
+}
+
+// Update works out the directions again for the tiles affected by the tile at pos changing:
+// the tile itself, the tiles next to it and, for a tile on the edge, the tile on the opposite
+// edge that a tunnel would lead to
+func (moves *MoveMap) Update(pos [2]int, getTileType func(pos [2]int) tile.TileType) {
+	update := func(pos [2]int) {
+		if moves.onMap(pos) {
+			moves.masks[pos[0]][pos[1]] = calcMask(pos, moves.size, getTileType)
+		}
+	}
+	update(pos)
+	for _, dir := range dirs {
+		next := [2]int{pos[0] + dir[0], pos[1] + dir[1]}
+		update(next)
+		if !moves.onMap(next) {
+			update(WrapPos(next, moves.size))
+		}
+	}
+}
+
+// CanMove returns true if an entity on pos can move one tile in dir. throughDoor lets ghosts
+// leaving the ghost house pass its door.
+func (moves *MoveMap) CanMove(pos [2]int, dir [2]int, throughDoor bool) bool {
+	mask := moves.GetMask(pos)
+	if throughDoor {
+		mask |= mask >> 4
+	}
+	bit := dirBit(dir)
+	return bit != 0 && mask&bit != 0
+}
+
+// Target returns the tile an entity on pos arrives on by moving one tile in dir, which is on the
+// other side of the map when going through a tunnel
+func (moves *MoveMap) Target(pos [2]int, dir [2]int) [2]int {
+	return WrapPos([2]int{pos[0] + dir[0], pos[1] + dir[1]}, moves.size)
+}
+
+// WrapPos returns pos moved onto the opposite edge of the map if it is just off an edge
+func WrapPos(pos [2]int, size [2]int) [2]int {
+	for i := range pos {
+		if pos[i] == -1 {
+			pos[i] = size[i] - 1
+		} else if pos[i] == size[i] {
+			pos[i] = 0
+		}
+	}
+	return pos
+}
+
+// the directions in the order of their bits
+var dirs = [4][2]int{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
+
+func dirBit(dir [2]int) uint8 {
+	for i, curDir := range dirs {
+		if curDir == dir {
+			return 1 << uint(i)
+		}
+	}
+	return 0
+}
+
+func (moves *MoveMap) onMap(pos [2]int) bool {
+	return pos[0] >= 0 && pos[1] >= 0 && pos[0] < moves.size[0] && pos[1] < moves.size[1]
+}
+
+// calcMask works out which directions can be moved in from pos. Walls block everything and the
+// ghost house door only lets through ghosts leaving the house.
+func calcMask(pos [2]int, size [2]int, getTileType func(pos [2]int) tile.TileType) uint8 {
+	var mask uint8
+	for i, dir := range dirs {
+		next := [2]int{pos[0] + dir[0], pos[1] + dir[1]}
+		nextType := tile.Wall
+		if next[0] >= 0 && next[1] >= 0 && next[0] < size[0] && next[1] < size[1] {
+			nextType = getTileType(next)
+		} else if exit, ok := tile.TunnelExit(next, size, getTileType); ok {
+			nextType = getTileType(exit)
+		}
+		switch nextType {
+		case tile.Wall:
+		case tile.GhostDoor:
+			mask |= 1 << uint(i+4)
+		default:
+			mask |= 1 << uint(i)
+		}
+	}
+	return mask
+}
@@ -0,0 +1,151 @@
+package movement
+
+// Mover moves an entity from tile to tile at a fixed speed. Movement only advances in Step so a
+// simulation run with the same inputs always ends up in the same place, rendering interpolates
+// between the positions of the last two steps.
+type Mover struct {
+	pos       [2]int
+	targetPos [2]int
+	// the direction of the current move, or of the last one when idle
+	dir [2]int
+	// how far along the move from pos to targetPos the entity is, from 0 to 1
+	progress float64
+	speed    float64
+	// set once a step passes without a move so an entity that stopped does not set off again the way it faced
+	stopped bool
+
+	prevDrawPos [2]float32
+	drawPos     [2]float32
+}
+
+func New(pos [2]int, speed float64) Mover {
+	mover := Mover{speed: speed}
+	mover.SetPos(pos)
+	return mover
+}
+
+// SetPos moves the entity straight to pos without animating and stops any move in progress
+func (mover *Mover) SetPos(pos [2]int) {
+	mover.pos = pos
+	mover.targetPos = [2]int{-1, -1}
+	mover.dir = [2]int{0, 0}
+	mover.progress = 0
+	mover.stopped = true
+	mover.drawPos = [2]float32{float32(pos[0]), float32(pos[1])}
+	mover.prevDrawPos = mover.drawPos
+}
+
+// SetSpeed sets how many tiles per second the entity moves
+func (mover *Mover) SetSpeed(speed float64) {
+	mover.speed = speed
+}
+
+func (mover *Mover) GetPos() [2]int {
+	return mover.pos
+}
+
+// GetTargetPos returns the tile the entity is moving to or {-1, -1} if it is not moving
+func (mover *Mover) GetTargetPos() [2]int {
+	return mover.targetPos
+}
+
+// GetDir returns the direction of the current move or of the last one if the entity is not moving
+func (mover *Mover) GetDir() [2]int {
+	return mover.dir
+}
+
+// SetDir changes the direction the entity is facing without moving it
+func (mover *Mover) SetDir(dir [2]int) {
+	mover.dir = dir
+}
+
+func (mover *Mover) IsMoving() bool {
+	return mover.targetPos[0] != -1 || mover.targetPos[1] != -1
+}
+
+// Start begins a move in dir that ends on targetPos. targetPos is normally the next tile in dir but
+// when going through a tunnel it is the tile on the other side of the map.
+func (mover *Mover) Start(targetPos [2]int, dir [2]int) {
+	mover.targetPos = targetPos
+	mover.dir = dir
+	mover.stopped = false
+}
+
+// Steer starts a move in the direction held by input if the entity is not already moving. When
+// that way is blocked an entity that has just reached a tile keeps going the way it was moving, so
+// a turn held before a corner is taken as soon as it opens. throughDoor lets ghosts leaving the
+// ghost house pass its door.
+func (mover *Mover) Steer(moves *MoveMap, input [2]int, throughDoor bool) {
+	if mover.IsMoving() || input == [2]int{0, 0} {
+		return
+	}
+	dir := input
+	if !moves.CanMove(mover.pos, dir, throughDoor) {
+		dir = mover.dir
+		if mover.stopped || !moves.CanMove(mover.pos, dir, throughDoor) {
+			return
+		}
+	}
+	mover.Start(moves.Target(mover.pos, dir), dir)
+}
+
+// Reverse turns the entity around in the middle of a move. It returns false and does nothing if
+// the entity is not moving or is going through a tunnel.
+func (mover *Mover) Reverse() bool {
+	if !mover.IsMoving() || mover.isWrapping() {
+		return false
+	}
+	mover.pos, mover.targetPos = mover.targetPos, mover.pos
+	mover.dir = [2]int{-mover.dir[0], -mover.dir[1]}
+	mover.progress = 1 - mover.progress
+	return true
+}
+
+// Step advances the move in progress by deltaTime seconds
+func (mover *Mover) Step(deltaTime float64) {
+	mover.prevDrawPos = mover.drawPos
+	if !mover.IsMoving() {
+		mover.progress = 0
+		mover.stopped = true
+		return
+	}
+	mover.progress += mover.speed * deltaTime
+	if mover.progress >= 1 {
+		// keep what is left over so the next move continues at the same speed
+		mover.progress--
+		mover.pos = mover.targetPos
+		mover.targetPos = [2]int{-1, -1}
+	}
+	mover.drawPos = mover.calcDrawPos()
+}
+
+// GetDrawPos returns where to draw the entity alpha of the way from the previous step to the current one
+func (mover *Mover) GetDrawPos(alpha float64) [2]float32 {
+	dist := [2]float32{mover.drawPos[0] - mover.prevDrawPos[0], mover.drawPos[1] - mover.prevDrawPos[1]}
+	if dist[0] > 1 || dist[0] < -1 || dist[1] > 1 || dist[1] < -1 {
+		// the entity jumped across the map through a tunnel
+		return mover.drawPos
+	}
+	return [2]float32{mover.prevDrawPos[0] + dist[0]*float32(alpha), mover.prevDrawPos[1] + dist[1]*float32(alpha)}
+}
+
+func (mover *Mover) isWrapping() bool {
+	return mover.targetPos[0] != mover.pos[0]+mover.dir[0] || mover.targetPos[1] != mover.pos[1]+mover.dir[1]
+}
+
+// calcDrawPos returns the position of the entity along its move. Half way through a tunnel it
+// leaves one edge of the map and appears on the other.
+func (mover *Mover) calcDrawPos() [2]float32 {
+	if !mover.IsMoving() {
+		return [2]float32{float32(mover.pos[0]), float32(mover.pos[1])}
+	}
+	drawPos := [2]float32{
+		float32(float64(mover.pos[0]) + float64(mover.dir[0])*mover.progress),
+		float32(float64(mover.pos[1]) + float64(mover.dir[1])*mover.progress),
+	}
+	if mover.progress >= 0.5 {
+		drawPos[0] += float32(mover.targetPos[0] - mover.pos[0] - mover.dir[0])
+		drawPos[1] += float32(mover.targetPos[1] - mover.pos[1] - mover.dir[1])
+	}
+	return drawPos
+}
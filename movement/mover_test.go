@@ -0,0 +1,170 @@
+package movement
+
+import (
+	"testing"
+
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+var (
+	up    = [2]int{0, -1}
+	left  = [2]int{-1, 0}
+	right = [2]int{1, 0}
+)
+
+// testMoves builds the move map of rows where # is a wall, T a tunnel, - a ghost house door and
+// anything else blank
+func testMoves(rows ...string) MoveMap {
+	size := [2]int{len(rows[0]), len(rows)}
+	return BuildMoveMap(size, func(pos [2]int) tile.TileType {
+		switch rows[pos[1]][pos[0]] {
+		case '#':
+			return tile.Wall
+		case 'T':
+			return tile.Tunnel
+		case '-':
+			return tile.GhostDoor
+		}
+		return tile.Blank
+	})
+}
+
+var testMaze = []string{
+	"#######",
+	"T.....T",
+	"#.#-#.#",
+	"#.....#",
+	"#######",
+}
+
+func TestMoverCornering(t *testing.T) {
+	moves := testMoves(testMaze...)
+	mover := New([2]int{4, 3}, 0.75)
+	mover.Steer(&moves, right, false)
+	mover.Step(1)
+	mover.Step(1)
+	if pos, moving := mover.GetPos(), mover.IsMoving(); pos != [2]int{5, 3} || moving {
+		t.Fatalf("mover is on %v moving %v, want on 5,3 and stopped", pos, moving)
+	}
+
+	// the turn keeps what was left over from the last move so the speed stays the same round the corner
+	mover.Steer(&moves, up, false)
+	if target, dir := mover.GetTargetPos(), mover.GetDir(); target != [2]int{5, 2} || dir != up {
+		t.Fatalf("mover is moving %v to %v, want up to 5,2", dir, target)
+	}
+	mover.Step(1)
+	if pos, progress := mover.GetPos(), mover.progress; pos != [2]int{5, 2} || progress != 0.25 {
+		t.Errorf("mover is on %v %v of the way to the next tile, want on 5,2 and 0.25", pos, progress)
+	}
+}
+
+func TestMoverBufferedTurn(t *testing.T) {
+	moves := testMoves(testMaze...)
+	mover := New([2]int{1, 3}, 1)
+	mover.Steer(&moves, right, false)
+
+	// holding up before the opening keeps the mover going right until it can turn
+	var path [][2]int
+	for i := 0; i < 20 && mover.GetPos() != [2]int{5, 1}; i++ {
+		mover.Step(0.5)
+		mover.Steer(&moves, up, false)
+		if len(path) == 0 || path[len(path)-1] != mover.GetPos() {
+			path = append(path, mover.GetPos())
+		}
+	}
+	want := [][2]int{{1, 3}, {2, 3}, {3, 3}, {4, 3}, {5, 3}, {5, 2}, {5, 1}}
+	if len(path) != len(want) {
+		t.Fatalf("path is %v, want %v", path, want)
+	}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Fatalf("path is %v, want %v", path, want)
+		}
+	}
+
+	// the ghost house door is a wall to the player so only ghosts can turn through it
+	for _, test := range []struct {
+		throughDoor bool
+		want        [2]int
+	}{{false, right}, {true, up}} {
+		mover := New([2]int{2, 3}, 1)
+		mover.Steer(&moves, right, false)
+		mover.Step(1)
+		mover.Steer(&moves, up, test.throughDoor)
+		if got := mover.GetDir(); got != test.want {
+			t.Errorf("turn at the door with throughDoor %v went %v, want %v", test.throughDoor, got, test.want)
+		}
+	}
+}
+
+func TestMoverStopped(t *testing.T) {
+	moves := testMoves(testMaze...)
+	mover := New([2]int{2, 3}, 1)
+	mover.Steer(&moves, right, false)
+	mover.Step(1)
+
+	// letting go stops the mover on the next tile and a blocked turn does not set it off again
+	mover.Steer(&moves, [2]int{0, 0}, false)
+	mover.Step(1)
+	mover.Steer(&moves, up, false)
+	if mover.IsMoving() {
+		t.Errorf("stopped mover set off %v holding a blocked direction", mover.GetDir())
+	}
+
+	// nor does a blocked direction after being placed on a tile
+	mover.SetPos([2]int{2, 3})
+	mover.SetDir(right)
+	mover.Steer(&moves, up, false)
+	if mover.IsMoving() {
+		t.Errorf("placed mover set off %v holding a blocked direction", mover.GetDir())
+	}
+
+	// walking into a wall stops the mover
+	mover.Steer(&moves, left, false)
+	for i := 0; i < 4; i++ {
+		mover.Step(1)
+		mover.Steer(&moves, left, false)
+	}
+	if pos, moving := mover.GetPos(), mover.IsMoving(); pos != [2]int{1, 3} || moving {
+		t.Errorf("mover is on %v moving %v, want stopped on 1,3 against the wall", pos, moving)
+	}
+}
+
+func TestMoverTunnelWrap(t *testing.T) {
+	moves := testMoves(testMaze...)
+	mover := New([2]int{0, 1}, 1)
+	mover.Steer(&moves, left, false)
+	if target := mover.GetTargetPos(); target != [2]int{6, 1} {
+		t.Fatalf("move left through the tunnel ends on %v, want 6,1", target)
+	}
+
+	mover.Step(0.25)
+	if drawPos := mover.GetDrawPos(1); drawPos != [2]float32{-0.25, 1} {
+		t.Errorf("draw position a quarter through the tunnel is %v, want -0.25,1", drawPos)
+	}
+	if mover.Reverse() {
+		t.Error("mover reversed in a tunnel")
+	}
+	// half way it appears on the other side without being drawn across the map
+	mover.Step(0.25)
+	if drawPos := mover.GetDrawPos(0.5); drawPos != [2]float32{6.5, 1} {
+		t.Errorf("draw position half way through the tunnel is %v, want 6.5,1", drawPos)
+	}
+	mover.Step(0.5)
+	if pos, moving := mover.GetPos(), mover.IsMoving(); pos != [2]int{6, 1} || moving {
+		t.Errorf("mover is on %v moving %v, want on 6,1 and stopped", pos, moving)
+	}
+
+	// out of the tunnel it can reverse again
+	mover.Steer(&moves, left, false)
+	mover.Step(0.25)
+	if !mover.Reverse() || mover.GetDir() != right || mover.GetTargetPos() != [2]int{6, 1} {
+		t.Errorf("mover is moving %v to %v after reversing, want right to 6,1", mover.GetDir(), mover.GetTargetPos())
+	}
+
+	// the tunnel only leads through when both ends are tunnels
+	blocked := testMoves("#T#", "#.#", "###")
+	if blocked.CanMove([2]int{1, 0}, up, false) {
+		t.Error("tunnel with no tunnel on the other side can be moved through")
+	}
+}
@@ -1,105 +1,58 @@
 package player
 
 import (
-	"github.com/go-gl/glfw/v3.2/glfw"
-
-	"github.com/sunkink29/3dpacman/input"
+	"github.com/sunkink29/3dpacman/movement"
 	"github.com/sunkink29/3dpacman/tile"
 )
 
-const speed = 5
+// speed in tiles per second used until the level sets one
+const defaultSpeed = 5
 
 type Player struct {
-	pos       [2]int
-	tile      tile.Tile
-	targetPos [2]int
-	targetDir [2]int
+	mover movement.Mover
+	tile  tile.Tile
 }
 
 func New(pos [2]int) Player {
 	tile := tile.NewTile(pos, 2, tile.PlayerTex, 0)
-	return Player{pos, tile, [2]int{-1, -1}, [2]int{0, 0}}
+	return Player{movement.New(pos, defaultSpeed), tile}
 }
 
 func (curPlayer *Player) SetPos(pos [2]int) {
-	curPlayer.pos = pos
-	curPlayer.tile.Pos = [2]float32{float32(curPlayer.pos[0]), float32(curPlayer.pos[1])}
+	curPlayer.mover.SetPos(pos)
 }
 
-func (curPlayer *Player) Render(deltaTime float64) {
-	if curPlayer.targetPos[0] != -1 && curPlayer.targetPos[1] != -1 {
-		var targetDist [2]float32
-		targetDist[0] = float32(curPlayer.targetPos[0]) - curPlayer.tile.Pos[0]
-		targetDist[1] = float32(curPlayer.targetPos[1]) - curPlayer.tile.Pos[1]
-		if targetDist[0]*float32(curPlayer.targetDir[0]) > 0 || targetDist[1]*float32(curPlayer.targetDir[1]) > 0 {
-			curPlayer.tile.Pos[0] += float32(float64(curPlayer.targetDir[0]) * deltaTime * speed)
-			curPlayer.tile.Pos[1] += float32(float64(curPlayer.targetDir[1]) * deltaTime * speed)
-		} else {
-			curPlayer.pos = curPlayer.targetPos
-			curPlayer.tile.Pos = [2]float32{float32(curPlayer.targetPos[0]), float32(curPlayer.targetPos[1])}
-			curPlayer.targetPos = [2]int{-1, -1}
-			curPlayer.targetDir = [2]int{0, 0}
-		}
-	}
-	curPlayer.tile.Render()
+// SetSpeed sets how many tiles per second the player moves
+func (curPlayer *Player) SetSpeed(speed float64) {
+	curPlayer.mover.SetSpeed(speed)
 }
 
-type GetMapTileType func(pos [2]int) tile.TileType
+func (curPlayer *Player) GetPos() [2]int {
+	return curPlayer.mover.GetPos()
+}
 
-func (curPlayer *Player) UpdatePlayerPos(mapSize [2]int, getTileType GetMapTileType) {
-	var nextTileType tile.TileType
-	var nextPos [2]int
-	nextPos[0] = curPlayer.pos[0] + movement[0]
-	nextPos[1] = curPlayer.pos[1] + movement[1]
-	if nextPos[0] >= 0 && nextPos[0] < mapSize[0] && nextPos[1] >= 0 && nextPos[1] < mapSize[1] {
-		nextTileType = getTileType(nextPos)
-	}
-	if nextTileType != tile.Wall && curPlayer.targetPos[0] == -1 && curPlayer.targetPos[1] == -1 &&
-		(movement[0] != 0 || movement[1] != 0) {
-		curPlayer.targetPos[0] = movement[0] + curPlayer.pos[0]
-		curPlayer.targetPos[1] = movement[1] + curPlayer.pos[1]
-		curPlayer.targetDir = movement
-	}
+// GetTargetPos returns the tile the player is moving to or {-1, -1} if it is not moving
+func (curPlayer *Player) GetTargetPos() [2]int {
+	return curPlayer.mover.GetTargetPos()
 }
 
-var movement [2]int
-var lastPress int
+// GetDir returns the direction the player last moved in
+func (curPlayer *Player) GetDir() [2]int {
+	return curPlayer.mover.GetDir()
+}
+
+// GetTile returns the tile to draw the player with alpha of the way between the last two simulation steps
+func (curPlayer *Player) GetTile(alpha float64) tile.Tile {
+	curPlayer.tile.Pos = curPlayer.mover.GetDrawPos(alpha)
+	return curPlayer.tile
+}
 
-func RegisterPlayerBindings() {
-	input.RegisterKeyBinding(glfw.KeyUp, "Move Player Up", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Press {
-			movement[0] = 0
-			movement[1] = -1
-			lastPress = 1
-		} else if action == glfw.Release && lastPress == 1 {
-			movement[1] = 0
-		}
-	})
-	input.RegisterKeyBinding(glfw.KeyDown, "Move Player Down", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Press {
-			movement[0] = 0
-			movement[1] = 1
-			lastPress = 2
-		} else if action == glfw.Release && lastPress == 2 {
-			movement[1] = 0
-		}
-	})
-	input.RegisterKeyBinding(glfw.KeyLeft, "Move Player Left", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Press {
-			movement[0] = -1
-			movement[1] = 0
-			lastPress = 3
-		} else if action == glfw.Release && lastPress == 3 {
-			movement[0] = 0
-		}
-	})
-	input.RegisterKeyBinding(glfw.KeyRight, "Move Player Right", func(w *glfw.Window, action glfw.Action, mods glfw.ModifierKey) {
-		if action == glfw.Press {
-			movement[0] = 1
-			movement[1] = 0
-			lastPress = 4
-		} else if action == glfw.Release && lastPress == 4 {
-			movement[0] = 0
-		}
-	})
+// Step starts a move in the direction held by input if the player is not already moving and then
+// advances the player by deltaTime seconds. While input is blocked the player keeps going the way
+// it was moving until it opens up. Which directions are open comes from moves, walls and the ghost
+// house door block the player and moves off the edge of the map through a tunnel wrap to the
+// opposite edge.
+func (curPlayer *Player) Step(deltaTime float64, moves *movement.MoveMap, input [2]int) {
+	curPlayer.mover.Steer(moves, input, false)
+	curPlayer.mover.Step(deltaTime)
 }
@@ -150,7 +150,11 @@ func NewTexture(file string) (uint32, error) {
 	if err != nil {
 		return 0, err
 	}
+	return NewImageTexture(img)
+}
 
+// NewImageTexture uploads img to a new texture bound to the first texture unit
+func NewImageTexture(img image.Image) (uint32, error) {
 	rgba := image.NewRGBA(img.Bounds())
 	if rgba.Stride != rgba.Rect.Size().X*4 {
 		return 0, fmt.Errorf("unsupported stride")
@@ -200,6 +204,13 @@ func ScreenToWorldSpace(window *glfw.Window, point [2]float64, matProjection mgl
 	return pos.Vec3()
 }
 
+// WorldToTextSpace returns where a point in the world appears on screen in the coordinates
+// text is positioned with, where the centre of the window is 0, 0 and y points up
+func WorldToTextSpace(point mgl32.Vec3, matProjection mgl32.Mat4) mgl32.Vec2 {
+	pos := matProjection.Mul4x1(point.Vec4(1))
+	return mgl32.Vec2{pos[0] / pos[3] * WindowWidth / 2, pos[1] / pos[3] * WindowHeight / 2}
+}
+
 var VertexShader = `
 #version 400
 uniform mat4 projection;
@@ -216,7 +227,7 @@ void main() {
 
 var TileFragShader = `
 #version 400
-uniform sampler2D tex[10];
+uniform sampler2D tex[16];
 uniform uint texIndex;
 uniform uint renderFlags;
 uniform vec4 inputColor;
@@ -262,3 +273,38 @@ void main() {
 	}
 }
 ` + "\x00"
+
+// SolidFragShader fills with a single color, used with VertexShader for guides drawn over the map
+var SolidFragShader = `
+#version 400
+uniform vec4 inputColor;
+in vec2 fragTexCoord;
+out vec4 outputColor;
+void main() {
+	outputColor = inputColor;
+}
+` + "\x00"
+
+// ImageVertexShader places a square from 0, 0 to 1, 1 over the part of the screen in rect, which
+// holds the corner and size in normalized device coordinates, so the image in tex can be drawn flat
+var ImageVertexShader = `
+#version 400
+uniform vec4 rect;
+in vec2 vert;
+out vec2 fragTexCoord;
+void main() {
+    fragTexCoord = vec2(vert.x, 1 - vert.y);
+    gl_Position = vec4(rect.xy + vert * rect.zw, 0, 1);
+}
+` + "\x00"
+
+// ImageFragShader draws the image in tex, used with ImageVertexShader
+var ImageFragShader = `
+#version 400
+uniform sampler2D tex;
+in vec2 fragTexCoord;
+out vec4 outputColor;
+void main() {
+	outputColor = texture(tex, fragTexCoord);
+}
+` + "\x00"
@@ -0,0 +1,66 @@
+package tiles
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/sunkink29/3dpacman/rendering"
+)
+
+// rectangles are drawn at this height so they cover the map and everything on it
+const rectHeight = 1
+
+var rVao, rProgram uint32
+
+// the view matrix given to SetUniforms, rectangles use it too
+var curViewMatrix mgl32.Mat4
+
+// initRect compiles the shader used to draw rectangles and sets it up to read the tile vertices from vbo
+func initRect(camera rendering.Camera, vbo uint32) {
+	program, err := rendering.NewProgram(rendering.VertexShader, rendering.SolidFragShader)
+	if err != nil {
+		panic(err)
+	}
+	gl.UseProgram(program)
+
+	projectionUniform := gl.GetUniformLocation(program, gl.Str("projection\x00"))
+	gl.UniformMatrix4fv(projectionUniform, 1, false, &camera.ProjectionMatrix[0])
+
+	gl.BindFragDataLocation(program, 0, gl.Str("outputColor\x00"))
+
+	var vao uint32
+	gl.GenVertexArrays(1, &vao)
+	gl.BindVertexArray(vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+
+	vertAttrib := uint32(gl.GetAttribLocation(program, gl.Str("vert\x00")))
+	gl.EnableVertexAttribArray(vertAttrib)
+	gl.VertexAttribPointer(vertAttrib, 3, gl.FLOAT, false, 5*4, gl.PtrOffset(0))
+
+	rVao = vao
+	rProgram = program
+}
+
+// RenderRect draws a flat rectangle of a single color over the map covering the world x and z
+// positions from minPos to maxPos. Tile centres are at whole numbers so a tile covers half a unit on
+// each side.
+func RenderRect(minPos mgl32.Vec2, maxPos mgl32.Vec2, color mgl32.Vec4) {
+	gl.UseProgram(rProgram)
+
+	cameraUniform := gl.GetUniformLocation(rProgram, gl.Str("camera\x00"))
+	gl.UniformMatrix4fv(cameraUniform, 1, false, &curViewMatrix[0])
+
+	center := minPos.Add(maxPos).Mul(0.5)
+	model := mgl32.Translate3D(center[0], rectHeight, center[1]).Mul4(mgl32.Scale3D(maxPos[0]-minPos[0], 1, maxPos[1]-minPos[1]))
+	modelUniform := gl.GetUniformLocation(rProgram, gl.Str("model\x00"))
+	gl.UniformMatrix4fv(modelUniform, 1, false, &model[0])
+
+	colorUniform := gl.GetUniformLocation(rProgram, gl.Str("inputColor\x00"))
+	gl.Uniform4fv(colorUniform, 1, &color[0])
+
+	gl.BindVertexArray(rVao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 2*3)
+
+	// leave the tile shader in use for Render
+	gl.UseProgram(tProgram)
+}
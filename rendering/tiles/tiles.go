@@ -0,0 +1,175 @@
+package tiles
+
+import (
+	"log"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/sunkink29/3dpacman/maps"
+	"github.com/sunkink29/3dpacman/rendering"
+	. "github.com/sunkink29/3dpacman/textures"
+	"github.com/sunkink29/3dpacman/tile"
+)
+
+type TypeData struct {
+	color    mgl32.Vec4
+	texIndex uint32
+}
+
+var typeDataList = []TypeData{
+	TypeData{mgl32.Vec4{0, 0, 0, 0}, 0},        // Blank
+	TypeData{mgl32.Vec4{0, 0, 1, 1}, 4},        // Wall
+	TypeData{mgl32.Vec4{1, 1, 0, 1}, 5},        // Dot
+	TypeData{mgl32.Vec4{1, 1, 0, 1}, 6},        // DotBig
+	TypeData{mgl32.Vec4{1, 1, 0, 1}, 7},        // playerTex
+	TypeData{mgl32.Vec4{0.1, 0.1, 0.1, 1}, 6},  // playerSpawn
+	TypeData{mgl32.Vec4{1, 0, 0, 1}, 8},        // blinkyTex
+	TypeData{mgl32.Vec4{1, 0.7, 0.8, 1}, 8},    // pinkyTex
+	TypeData{mgl32.Vec4{0, 1, 1, 1}, 8},        // inkyTex
+	TypeData{mgl32.Vec4{1, 0.7, 0.3, 1}, 8},    // clydeTex
+	TypeData{mgl32.Vec4{0.2, 0.2, 1, 1}, 8},    // frightenedTex
+	TypeData{mgl32.Vec4{0.5, 0.5, 0.5, 1}, 9},  // tunnel
+	TypeData{mgl32.Vec4{0.3, 0.3, 0.3, 1}, 10}, // ghostHouse
+	TypeData{mgl32.Vec4{1, 0.7, 0.8, 1}, 11},   // ghostDoor
+	TypeData{mgl32.Vec4{0.1, 0.1, 0.1, 1}, 12}, // fruitSpawn
+	TypeData{mgl32.Vec4{1, 0, 0, 1}, 12},       // fruitTex
+}
+
+var tVao, tProgram uint32
+
+// Init compiles the tile shader and loads the tile textures
+func Init(camera rendering.Camera) {
+	// Configure the vertex and fragment shaders
+	program, err := rendering.NewProgram(rendering.VertexShader, rendering.TileFragShader)
+	if err != nil {
+		panic(err)
+	}
+	gl.UseProgram(program)
+
+	projectionUniform := gl.GetUniformLocation(program, gl.Str("projection\x00"))
+	gl.UniformMatrix4fv(projectionUniform, 1, false, &camera.ProjectionMatrix[0])
+
+	gl.BindFragDataLocation(program, 0, gl.Str("outputColor\x00"))
+
+	// Configure the vertex data
+	var vao uint32
+	gl.GenVertexArrays(1, &vao)
+	gl.BindVertexArray(vao)
+
+	var vbo uint32
+	gl.GenBuffers(1, &vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(planeVertices)*4, gl.Ptr(planeVertices), gl.STATIC_DRAW)
+
+	vertAttrib := uint32(gl.GetAttribLocation(program, gl.Str("vert\x00")))
+	gl.EnableVertexAttribArray(vertAttrib)
+	gl.VertexAttribPointer(vertAttrib, 3, gl.FLOAT, false, 5*4, gl.PtrOffset(0))
+
+	texCoordAttrib := uint32(gl.GetAttribLocation(program, gl.Str("vertTexCoord\x00")))
+	gl.EnableVertexAttribArray(texCoordAttrib)
+	gl.VertexAttribPointer(texCoordAttrib, 2, gl.FLOAT, false, 5*4, gl.PtrOffset(3*4))
+
+	borderWidthUniform := gl.GetUniformLocation(program, gl.Str("borderWidth\x00"))
+	gl.Uniform1f(borderWidthUniform, 0.03)
+
+	aspectUniform := gl.GetUniformLocation(program, gl.Str("aspect\x00"))
+	gl.Uniform1f(aspectUniform, 1)
+
+	// Load the textures
+	textureFileNames := TextureFilenames
+
+	for i, fileName := range textureFileNames {
+		texture, err := rendering.NewTexture(TextureDir + fileName + ".png")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		gl.ActiveTexture(gl.TEXTURE1 + uint32(i))
+		gl.BindTexture(gl.TEXTURE_2D, texture)
+	}
+
+	tVao = vao
+	tProgram = program
+	initRect(camera, vbo)
+}
+
+func SetUniforms(viewMatrix mgl32.Mat4) {
+	curViewMatrix = viewMatrix
+	gl.UseProgram(tProgram)
+
+	cameraUniform := gl.GetUniformLocation(tProgram, gl.Str("camera\x00"))
+	gl.UniformMatrix4fv(cameraUniform, 1, false, &viewMatrix[0])
+
+	textureIndexs := make([]int32, 0)
+	for index := range TextureFilenames {
+		textureIndexs = append(textureIndexs, int32(index+1))
+	}
+
+	textureUniform := gl.GetUniformLocation(tProgram, gl.Str("tex\x00"))
+	gl.Uniform1iv(textureUniform, int32(len(textureIndexs)), &textureIndexs[0])
+
+	wireframeUniform := gl.GetUniformLocation(tProgram, gl.Str("renderWireframe\x00"))
+	gl.Uniform1i(wireframeUniform, rendering.RenderWireframe)
+}
+
+func Render(curTile tile.Tile) {
+	model := mgl32.Translate3D(curTile.Pos[0], float32(curTile.GetLayer()-3), curTile.Pos[1])
+	modelUniform := gl.GetUniformLocation(tProgram, gl.Str("model\x00"))
+	gl.UniformMatrix4fv(modelUniform, 1, false, &model[0])
+
+	texIndexUniform := gl.GetUniformLocation(tProgram, gl.Str("texIndex\x00"))
+	gl.Uniform1ui(texIndexUniform, typeDataList[curTile.Type].texIndex)
+
+	renderFlagsUniform := gl.GetUniformLocation(tProgram, gl.Str("renderFlags\x00"))
+	if curTile.Type != tile.Wall {
+		curTile.Flags &= tile.All ^ 0xFFFF
+	}
+	gl.Uniform1ui(renderFlagsUniform, uint32(curTile.Flags))
+
+	colorUniform := gl.GetUniformLocation(tProgram, gl.Str("inputColor\x00"))
+	gl.Uniform4fv(colorUniform, 1, &typeDataList[curTile.Type].color[0])
+
+	gl.BindVertexArray(tVao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 2*3)
+}
+
+func GetTypeDataList() []TypeData {
+	return typeDataList
+}
+
+var planeVertices = []float32{
+	//  X, Y, Z, U, V
+	-0.5, 0.5, -0.5, 0.0, 0.0,
+	-0.5, 0.5, 0.5, 0.0, 1.0,
+	0.5, 0.5, -0.5, 1.0, 0.0,
+	0.5, 0.5, -0.5, 1.0, 0.0,
+	-0.5, 0.5, 0.5, 0.0, 1.0,
+	0.5, 0.5, 0.5, 1.0, 1.0,
+}
+
+// RenderMap draws the tiles of a map along with the fruit, player and ghosts on it.
+// The layers are drawn in their render order, each covering the ones drawn before it, so the
+// depth test has to let tiles at the same height through. Blank tiles are only drawn on the
+// bottom layer.
+// The player and ghosts are drawn alpha of the way between the last two simulation steps.
+func RenderMap(curMap *maps.Map, alpha float64) {
+	size := curMap.GetSize()
+	for i, layer := range maps.RenderOrder() {
+		for x := 0; x < size[0]; x++ {
+			for y := 0; y < size[1]; y++ {
+				curTile := curMap.GetLayerTile(layer, [2]int{x, y})
+				if i == 0 || curTile.Type != tile.Blank {
+					Render(curTile)
+				}
+			}
+		}
+	}
+	if fruitPos, ok := curMap.GetFruitSpawn(); ok && curMap.IsFruitActive() {
+		Render(tile.NewTile(fruitPos, 1, tile.FruitTex, 0))
+	}
+	Render(curMap.GetPlayer().GetTile(alpha))
+	ghosts := curMap.GetGhosts()
+	for i := range ghosts {
+		Render(ghosts[i].GetTile(alpha))
+	}
+}
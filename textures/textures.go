@@ -2,7 +2,7 @@ package textures
 
 const TextureDir = "assets/textures/"
 
-var TextureFilenames = []string{"wallUp", "wallDown", "wallLeft", "wallRight", "wallAuto", "dot", "bigDot", "pacman"}
+var TextureFilenames = []string{"wallUp", "wallDown", "wallLeft", "wallRight", "wallAuto", "dot", "bigDot", "pacman", "ghost", "tunnel", "ghostHouse", "ghostDoor", "fruit"}
 
 /*
 const (
@@ -1,14 +1,6 @@
 package tile
 
-import (
-	"log"
-
-	"github.com/go-gl/gl/v4.1-core/gl"
-	"github.com/go-gl/mathgl/mgl32"
-
-	"github.com/sunkink29/3dpacman/rendering"
-	. "github.com/sunkink29/3dpacman/textures"
-)
+import "strconv"
 
 type TileType uint16
 
@@ -19,20 +11,65 @@ const (
 	DotBig
 	PlayerTex
 	PlayerSpawn
+	BlinkyTex
+	PinkyTex
+	InkyTex
+	ClydeTex
+	FrightenedTex
+	Tunnel
+	GhostHouse
+	GhostDoor
+	FruitSpawn
+	FruitTex
+	// the number of tile types, keep this last
+	numTileTypes
 )
 
-type TypeData struct {
-	color    mgl32.Vec4
-	texIndex uint32
+// the names used for each tile type in files meant to be edited by people or other tools
+var typeNames = [numTileTypes]string{
+	"blank",
+	"wall",
+	"dot",
+	"bigDot",
+	"player",
+	"playerSpawn",
+	"blinky",
+	"pinky",
+	"inky",
+	"clyde",
+	"frightened",
+	"tunnel",
+	"ghostHouse",
+	"ghostDoor",
+	"fruitSpawn",
+	"fruit",
+}
+
+// IsValid returns true if tileType is one of the tile types above
+func (tileType TileType) IsValid() bool {
+	return tileType < numTileTypes
 }
 
-var typeDataList = []TypeData{
-	TypeData{mgl32.Vec4{0, 0, 0, 0}, 0},       // Blank
-	TypeData{mgl32.Vec4{0, 0, 1, 1}, 4},       // Wall
-	TypeData{mgl32.Vec4{1, 1, 0, 1}, 5},       // Dot
-	TypeData{mgl32.Vec4{1, 1, 0, 1}, 6},       // DotBig
-	TypeData{mgl32.Vec4{1, 1, 0, 1}, 7},       // playerTex
-	TypeData{mgl32.Vec4{0.1, 0.1, 0.1, 1}, 6}, // playerSpawn
+func (tileType TileType) String() string {
+	if !tileType.IsValid() {
+		return "TileType(" + strconv.Itoa(int(tileType)) + ")"
+	}
+	return typeNames[tileType]
+}
+
+// ParseTileType returns the tile type with the given name
+func ParseTileType(name string) (TileType, bool) {
+	for i, typeName := range typeNames {
+		if typeName == name {
+			return TileType(i), true
+		}
+	}
+	return Blank, false
+}
+
+// TypeNames returns the names of all the tile types in order
+func TypeNames() []string {
+	return append([]string(nil), typeNames[:]...)
 }
 
 type TileFlag uint16
@@ -45,125 +82,96 @@ const (
 	All = 0xF
 )
 
-// Tile holds the vao and program pointers
-type Tile struct {
-	Pos   [2]float32
-	layer int
-	Type  TileType
-	Flags TileFlag
-	// when type is wall the first four bits are wall directions otherwise
-	//   the first four bits represent the directions next to the current tile that are not of type wall
+// FlipLeftRight returns the flags with left and right swapped, for a tile mirrored across a vertical line
+func (flags TileFlag) FlipLeftRight() TileFlag {
+	return flags&^(Left|Right) | (flags&Left)<<1 | (flags&Right)>>1
 }
 
-func NewTile(pos [2]int, layer int, ttype TileType, flags TileFlag) Tile {
-
-	return Tile{[2]float32{float32(pos[0]), float32(pos[1])}, layer, ttype, flags}
+// FlipUpDown returns the flags with up and down swapped, for a tile mirrored across a horizontal line
+func (flags TileFlag) FlipUpDown() TileFlag {
+	return flags&^(Up|Down) | (flags&Up)<<1 | (flags&Down)>>1
 }
 
-var tVao, tProgram uint32
-
-func InitTileRendering(camera rendering.Camera) {
-	// Configure the vertex and fragment shaders
-	program, err := rendering.NewProgram(rendering.VertexShader, rendering.TileFragShader)
-	if err != nil {
-		panic(err)
+// RotateClockwise returns the flags turned a quarter turn clockwise, up becoming right, for a tile
+// rotated with the tiles around it
+func (flags TileFlag) RotateClockwise() TileFlag {
+	rotated := flags &^ All
+	if flags&Up != 0 {
+		rotated |= Right
 	}
-	gl.UseProgram(program)
-
-	projectionUniform := gl.GetUniformLocation(program, gl.Str("projection\x00"))
-	gl.UniformMatrix4fv(projectionUniform, 1, false, &camera.ProjectionMatrix[0])
-
-	gl.BindFragDataLocation(program, 0, gl.Str("outputColor\x00"))
-
-	// Configure the vertex data
-	var vao uint32
-	gl.GenVertexArrays(1, &vao)
-	gl.BindVertexArray(vao)
-
-	var vbo uint32
-	gl.GenBuffers(1, &vbo)
-	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
-	gl.BufferData(gl.ARRAY_BUFFER, len(planeVertices)*4, gl.Ptr(planeVertices), gl.STATIC_DRAW)
-
-	vertAttrib := uint32(gl.GetAttribLocation(program, gl.Str("vert\x00")))
-	gl.EnableVertexAttribArray(vertAttrib)
-	gl.VertexAttribPointer(vertAttrib, 3, gl.FLOAT, false, 5*4, gl.PtrOffset(0))
-
-	texCoordAttrib := uint32(gl.GetAttribLocation(program, gl.Str("vertTexCoord\x00")))
-	gl.EnableVertexAttribArray(texCoordAttrib)
-	gl.VertexAttribPointer(texCoordAttrib, 2, gl.FLOAT, false, 5*4, gl.PtrOffset(3*4))
-
-	borderWidthUniform := gl.GetUniformLocation(program, gl.Str("borderWidth\x00"))
-	gl.Uniform1f(borderWidthUniform, 0.03)
-
-	aspectUniform := gl.GetUniformLocation(program, gl.Str("aspect\x00"))
-	gl.Uniform1f(aspectUniform, 1)
+	if flags&Right != 0 {
+		rotated |= Down
+	}
+	if flags&Down != 0 {
+		rotated |= Left
+	}
+	if flags&Left != 0 {
+		rotated |= Up
+	}
+	return rotated
+}
 
-	// Load the textures
-	textureFileNames := TextureFilenames
+// the names of the direction flags in the order of their bits
+var flagNames = [...]string{"up", "down", "left", "right"}
 
-	for i, fileName := range textureFileNames {
-		texture, err := rendering.NewTexture(TextureDir + fileName + ".png")
-		if err != nil {
-			log.Fatalln(err)
+// Names returns the names of the direction flags that are set
+func (flags TileFlag) Names() []string {
+	names := make([]string, 0, len(flagNames))
+	for i, name := range flagNames {
+		if flags&(1<<uint(i)) != 0 {
+			names = append(names, name)
 		}
-		gl.ActiveTexture(gl.TEXTURE1 + uint32(i))
-		gl.BindTexture(gl.TEXTURE_2D, texture)
 	}
-
-	tVao = vao
-	tProgram = program
+	return names
 }
 
-func SetTileUniforms(viewMatrix mgl32.Mat4) {
-	gl.UseProgram(tProgram)
-
-	cameraUniform := gl.GetUniformLocation(tProgram, gl.Str("camera\x00"))
-	gl.UniformMatrix4fv(cameraUniform, 1, false, &viewMatrix[0])
-
-	textureIndexs := make([]int32, 0)
-	for index := range TextureFilenames {
-		textureIndexs = append(textureIndexs, int32(index+1))
+// ParseTileFlag returns the direction flag with the given name
+func ParseTileFlag(name string) (TileFlag, bool) {
+	for i, flagName := range flagNames {
+		if flagName == name {
+			return 1 << uint(i), true
+		}
 	}
-
-	textureUniform := gl.GetUniformLocation(tProgram, gl.Str("tex\x00"))
-	gl.Uniform1iv(textureUniform, int32(len(textureIndexs)), &textureIndexs[0])
-
-	wireframeUniform := gl.GetUniformLocation(tProgram, gl.Str("renderWireframe\x00"))
-	gl.Uniform1i(wireframeUniform, rendering.RenderWireframe)
+	return 0, false
 }
 
-func (tile Tile) Render() {
-	model := mgl32.Translate3D(tile.Pos[0], float32(tile.layer-3), tile.Pos[1])
-	modelUniform := gl.GetUniformLocation(tProgram, gl.Str("model\x00"))
-	gl.UniformMatrix4fv(modelUniform, 1, false, &model[0])
-
-	texIndexUniform := gl.GetUniformLocation(tProgram, gl.Str("texIndex\x00"))
-	gl.Uniform1ui(texIndexUniform, typeDataList[tile.Type].texIndex)
-
-	renderFlagsUniform := gl.GetUniformLocation(tProgram, gl.Str("renderFlags\x00"))
-	if tile.Type != Wall {
-		tile.Flags &= All ^ 0xFFFF
-	}
-	gl.Uniform1ui(renderFlagsUniform, uint32(tile.Flags))
+// Tile holds the position, type and flags of a square on the map
+type Tile struct {
+	Pos   [2]float32
+	layer int
+	Type  TileType
+	Flags TileFlag
+	// when type is wall the first four bits are wall directions otherwise
+	//   the first four bits represent the directions next to the current tile that are not of type wall
+}
 
-	colorUniform := gl.GetUniformLocation(tProgram, gl.Str("inputColor\x00"))
-	gl.Uniform4fv(colorUniform, 1, &typeDataList[tile.Type].color[0])
+func NewTile(pos [2]int, layer int, ttype TileType, flags TileFlag) Tile {
 
-	gl.BindVertexArray(tVao)
-	gl.DrawArrays(gl.TRIANGLES, 0, 2*3)
+	return Tile{[2]float32{float32(pos[0]), float32(pos[1])}, layer, ttype, flags}
 }
 
-func GetTypeDataList() []TypeData {
-	return typeDataList
+// GetLayer returns the height the tile is drawn at
+func (tile Tile) GetLayer() int {
+	return tile.layer
 }
 
-var planeVertices = []float32{
-	//  X, Y, Z, U, V
-	-0.5, 0.5, -0.5, 0.0, 0.0,
-	-0.5, 0.5, 0.5, 0.0, 1.0,
-	0.5, 0.5, -0.5, 1.0, 0.0,
-	0.5, 0.5, -0.5, 1.0, 0.0,
-	-0.5, 0.5, 0.5, 0.0, 1.0,
-	0.5, 0.5, 0.5, 1.0, 1.0,
+// TunnelExit returns the tile an entity arrives on when it steps off the edge of the map onto pos.
+// It only succeeds when the edge tile it left from and the tile on the opposite edge are both tunnels.
+func TunnelExit(pos [2]int, mapSize [2]int, getTileType func(pos [2]int) TileType) ([2]int, bool) {
+	from, exit := pos, pos
+	for i := range pos {
+		if pos[i] == -1 {
+			from[i] = 0
+			exit[i] = mapSize[i] - 1
+		} else if pos[i] == mapSize[i] {
+			from[i] = mapSize[i] - 1
+			exit[i] = 0
+		} else if pos[i] < -1 || pos[i] > mapSize[i] {
+			return pos, false
+		}
+	}
+	if from == pos || from[0] != pos[0] && from[1] != pos[1] {
+		return pos, false
+	}
+	return exit, getTileType(from) == Tunnel && getTileType(exit) == Tunnel
 }